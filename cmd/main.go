@@ -2,45 +2,423 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/backfill"
+	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/checkpoint"
 	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/config"
 	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/gitlab"
 	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/otel"
+	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/poller"
 	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/spans"
+	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/webhook"
 )
 
 func main() {
 	ctx := context.Background()
 
+	command, args := "export", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	var backfillOpts *backfillFlags
+	validateFor := command
+	switch command {
+	case "export", "capture", "serve", "poll", "replay":
+	case "backfill":
+		backfillOpts = newBackfillFlags(flags)
+	case "validate-config":
+		flags.StringVar(&validateFor, "command", "export", "command to validate the configuration for")
+	default:
+		log.Fatalf("unknown command: %s (supported: export, capture, serve, poll, backfill, replay, validate-config)", command)
+	}
+
 	fmt.Println("Starting GitLab OpenTelemetry Exporter")
 
 	// Load configuration
-	cfg := config.Load()
+	cfg, err := config.Load(flags, args)
+	if err == nil {
+		err = cfg.ValidateCommand(validateFor)
+	}
+	if command == "validate-config" {
+		runValidateConfig(validateFor, err)
+		return
+	}
+	if err != nil {
+		log.Fatalf("invalid configuration:\n%v", err)
+	}
 
-	// Initialize tracer
-	tp, err := otel.InitTracer(ctx, cfg)
+	switch command {
+	case "export":
+		runExport(ctx, cfg)
+	case "capture":
+		runCapture(ctx, cfg)
+	case "serve":
+		runServe(ctx, cfg)
+	case "poll":
+		runPoll(ctx, cfg)
+	case "backfill":
+		runBackfill(ctx, cfg, backfillOpts)
+	case "replay":
+		runReplay(ctx, cfg, flags.Args())
+	}
+}
+
+// runValidateConfig reports whether the configuration is valid for a command,
+// listing every problem found
+func runValidateConfig(command string, err error) {
 	if err != nil {
-		log.Fatalf("failed to initialize tracer: %v", err)
+		fmt.Printf("Configuration is invalid for %s:\n", command)
+		for _, line := range strings.Split(err.Error(), "\n") {
+			fmt.Printf("  - %s\n", line)
+		}
+		os.Exit(1)
+	}
+	fmt.Printf("Configuration is valid for %s\n", command)
+}
+
+// runExport exports the pipeline the exporter runs in, from a CI job, or a
+// pipeline captured earlier when a capture directory is set
+func runExport(ctx context.Context, cfg *config.Config) {
+	shutdown := initTelemetry(ctx, cfg)
+	defer shutdown()
+
+	var source gitlab.PipelineSource
+	if cfg.CaptureDir != "" {
+		fmt.Printf("Reading captured GitLab API responses from %s\n", cfg.CaptureDir)
+		source = gitlab.NewFileSource(cfg.CaptureDir)
+	} else {
+		gitClient, err := gitlab.NewClient(cfg)
+		if err != nil {
+			log.Printf("failed to create GitLab client: %v", err)
+			shutdown()
+			os.Exit(1)
+		}
+		source = gitClient
+	}
+
+	// Create and run exporter. On failure the telemetry is shut down before
+	// exiting, as os.Exit skips deferred calls, so the spans and log records
+	// built before the error are still flushed.
+	exporter := spans.NewExporter(cfg, source)
+	if err := exporter.ExportPipeline(ctx); err != nil {
+		log.Printf("failed to export trace: %v", err)
+		shutdown()
+		os.Exit(1)
+	}
+
+	if !cfg.DryRun {
+		fmt.Println("Traces exported successfully")
+	}
+}
+
+// runCapture saves the GitLab API responses an export of the pipeline reads,
+// so it can be exported later without GitLab. No telemetry is initialized,
+// so the spans built along the way are discarded.
+//
+// Job logs, and job needs when the token can read them, are captured whatever
+// the settings, so a later export can turn on the features reading them.
+func runCapture(ctx context.Context, cfg *config.Config) {
+	cfg.JobSections = true
+	cfg.JobNeeds = cfg.TokenType == "private"
+
+	gitClient, err := gitlab.NewClient(cfg)
+	if err != nil {
+		log.Fatalf("failed to create GitLab client: %v", err)
 	}
-	defer func() {
-		if err := tp.Shutdown(ctx); err != nil {
-			log.Printf("error shutting down tracer: %v", err)
+	recorder, err := gitlab.NewRecorder(gitClient, cfg.CaptureDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := spans.NewExporter(cfg, recorder).ExportPipeline(ctx); err != nil {
+		log.Fatalf("failed to capture pipeline: %v", err)
+	}
+
+	fmt.Printf("GitLab API responses saved to %s\n", cfg.CaptureDir)
+}
+
+// runServe receives GitLab webhooks and exports pipelines once they finish
+func runServe(ctx context.Context, cfg *config.Config) {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	shutdown := initTelemetry(ctx, cfg)
+	defer shutdown()
+
+	gitClient, err := gitlab.NewClient(cfg)
+	if err != nil {
+		log.Fatalf("failed to create GitLab client: %v", err)
+	}
+
+	hooks := webhook.NewServer(cfg, gitClient, func(ctx context.Context, projectID string, pipelineID int) error {
+		return spans.NewExporter(cfg, gitClient).ExportPipelineByID(ctx, projectID, pipelineID)
+	})
+	hooks.Start(context.WithoutCancel(ctx))
+
+	mux := http.NewServeMux()
+	mux.Handle("/webhook", hooks)
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	server := &http.Server{
+		Addr:              cfg.ListenAddr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		fmt.Printf("Listening for GitLab webhooks on %s/webhook\n", cfg.ListenAddr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("webhook server failed: %v", err)
 		}
 	}()
 
-	// Create GitLab client
+	<-ctx.Done()
+	fmt.Println("Shutting down, finishing queued exports...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("error shutting down webhook server: %v", err)
+	}
+	hooks.Stop()
+}
+
+// runPoll polls the configured projects and groups and exports pipelines once
+// they finish, remembering exported pipelines in the checkpoint file
+func runPoll(ctx context.Context, cfg *config.Config) {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	shutdown := initTelemetry(ctx, cfg)
+	defer shutdown()
+
 	gitClient, err := gitlab.NewClient(cfg)
 	if err != nil {
 		log.Fatalf("failed to create GitLab client: %v", err)
 	}
 
-	// Create and run exporter
-	exporter := spans.NewExporter(cfg, gitClient)
-	if err := exporter.ExportPipeline(ctx); err != nil {
-		log.Fatalf("failed to export trace: %v", err)
+	cp, err := checkpoint.Load(cfg.CheckpointFile)
+	if err != nil {
+		log.Fatalf("failed to load checkpoint: %v", err)
+	}
+
+	fmt.Printf("Polling for finished pipelines every %s\n", cfg.PollInterval)
+	p := poller.New(cfg, gitClient, cp, func(ctx context.Context, projectID string, pipelineID int) error {
+		return spans.NewExporter(cfg, gitClient).ExportPipelineByID(ctx, projectID, pipelineID)
+	})
+	if err := p.Run(ctx); err != nil {
+		log.Printf("poller failed: %v", err)
+		shutdown()
+		os.Exit(1)
 	}
+}
+
+// backfillFlags holds the flags of the backfill command that select the
+// pipelines to export
+type backfillFlags struct {
+	projects string
+	groups   string
+	since    string
+	until    string
+	ref      string
+	status   string
+	workers  int
+}
+
+func newBackfillFlags(flags *flag.FlagSet) *backfillFlags {
+	f := &backfillFlags{}
+	flags.StringVar(&f.projects, "project", "", "comma-separated project paths or IDs")
+	flags.StringVar(&f.groups, "group", "", "comma-separated group paths or IDs")
+	flags.StringVar(&f.since, "since", "", "export pipelines updated at or after this date (YYYY-MM-DD or RFC 3339)")
+	flags.StringVar(&f.until, "until", "", "export pipelines updated before this date (default now)")
+	flags.StringVar(&f.ref, "ref", "", "only export pipelines of this branch or tag")
+	flags.StringVar(&f.status, "status", "", "only export pipelines with this status")
+	flags.IntVar(&f.workers, "workers", 4, "number of concurrent exports")
+	return f
+}
 
-	fmt.Println("Traces exported successfully")
+// runBackfill exports the finished pipelines of a date range, resuming from
+// the checkpoint file when it was interrupted
+func runBackfill(ctx context.Context, cfg *config.Config, opts *backfillFlags) {
+	if opts.projects == "" && opts.groups == "" {
+		log.Fatal("-project or -group must be set for backfill")
+	}
+	if opts.since == "" {
+		log.Fatal("-since must be set for backfill")
+	}
+	since, err := parseDate(opts.since)
+	if err != nil {
+		log.Fatalf("invalid -since: %v", err)
+	}
+	until := time.Now()
+	if opts.until != "" {
+		if until, err = parseDate(opts.until); err != nil {
+			log.Fatalf("invalid -until: %v", err)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	shutdown := initTelemetry(ctx, cfg)
+	defer shutdown()
+
+	gitClient, err := gitlab.NewClient(cfg)
+	if err != nil {
+		log.Fatalf("failed to create GitLab client: %v", err)
+	}
+
+	cp, err := checkpoint.Load(cfg.CheckpointFile)
+	if err != nil {
+		log.Fatalf("failed to load checkpoint: %v", err)
+	}
+
+	b := backfill.New(gitClient, cp, func(ctx context.Context, projectID string, pipelineID int) error {
+		return spans.NewExporter(cfg, gitClient).ExportPipelineByID(ctx, projectID, pipelineID)
+	})
+	summary, err := b.Run(ctx, backfill.Options{
+		Projects: splitList(opts.projects),
+		Groups:   splitList(opts.groups),
+		Filter: gitlab.PipelineFilter{
+			UpdatedAfter:  &since,
+			UpdatedBefore: &until,
+			Ref:           opts.ref,
+			Status:        opts.status,
+		},
+		Workers: opts.workers,
+	})
+	fmt.Printf("Backfill summary: %s\n", summary)
+	if err != nil {
+		log.Printf("backfill stopped: %v", err)
+	}
+	if err != nil || summary.Failed > 0 {
+		shutdown()
+		os.Exit(1)
+	}
+}
+
+// runReplay sends OTLP/JSON files written with the file protocol to the
+// configured collector
+func runReplay(ctx context.Context, cfg *config.Config, files []string) {
+	if len(files) == 0 {
+		log.Fatal("usage: replay [flags] file...")
+	}
+
+	conn, err := otel.NewConnection(cfg)
+	if err != nil {
+		log.Fatalf("failed to configure OTLP connection: %v", err)
+	}
+	endpoint := cfg.SignalEndpoint("traces")
+	client, err := otel.CreateTraceClient(cfg.Protocol, endpoint, conn)
+	if err != nil {
+		log.Fatalf("failed to create OTLP client: %v", err)
+	}
+	if err := client.Start(ctx); err != nil {
+		log.Fatalf("failed to start OTLP client: %v", err)
+	}
+	stop := func() {
+		if err := client.Stop(context.WithoutCancel(ctx)); err != nil {
+			log.Printf("error stopping OTLP client: %v", err)
+		}
+	}
+	defer stop()
+
+	fmt.Printf("Replaying %d files to %s (protocol: %s)\n", len(files), endpoint, cfg.Protocol)
+	failed := false
+	for _, path := range files {
+		requests, spans, err := replayFile(ctx, client, path)
+		if err != nil {
+			log.Printf("failed to replay %s after %d requests: %v", path, requests, err)
+			failed = true
+			continue
+		}
+		fmt.Printf("Replayed %s: %d requests, %d spans\n", path, requests, spans)
+	}
+	if failed {
+		stop()
+		os.Exit(1)
+	}
+}
+
+func replayFile(ctx context.Context, client otlptrace.Client, path string) (requests, spans int, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer file.Close()
+	return otel.Replay(ctx, client, file)
+}
+
+// parseDate parses a date given as YYYY-MM-DD (midnight UTC) or RFC 3339
+func parseDate(value string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// initTelemetry initializes the tracer and the optional logger and meter,
+// returning a function that flushes and shuts them down
+func initTelemetry(ctx context.Context, cfg *config.Config) func() {
+	var shutdowns []func(context.Context) error
+
+	// Initialize tracer
+	tp, err := otel.InitTracer(ctx, cfg)
+	if err != nil {
+		log.Fatalf("failed to initialize tracer: %v", err)
+	}
+	shutdowns = append(shutdowns, tp.Shutdown)
+
+	// Initialize logger for job log export. Only the trace tree is
+	// rendered in a dry run.
+	if cfg.ExportLogs && !cfg.DryRun {
+		lp, err := otel.InitLogger(ctx, cfg)
+		if err != nil {
+			log.Fatalf("failed to initialize logger: %v", err)
+		}
+		shutdowns = append(shutdowns, lp.Shutdown)
+	}
+
+	// Initialize meter for pipeline and job metrics
+	if cfg.ExportMetrics && !cfg.DryRun {
+		mp, err := otel.InitMeter(ctx, cfg)
+		if err != nil {
+			log.Fatalf("failed to initialize meter: %v", err)
+		}
+		shutdowns = append(shutdowns, mp.Shutdown)
+	}
+
+	return func() {
+		ctx := context.WithoutCancel(ctx)
+		for i := len(shutdowns) - 1; i >= 0; i-- {
+			if err := shutdowns[i](ctx); err != nil {
+				log.Printf("error shutting down telemetry: %v", err)
+			}
+		}
+	}
 }
@@ -0,0 +1,154 @@
+package backfill
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/checkpoint"
+	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/gitlab"
+)
+
+// ExportFunc exports the trace of one pipeline
+type ExportFunc func(ctx context.Context, projectID string, pipelineID int) error
+
+// Options selects the pipelines to backfill
+type Options struct {
+	Projects []string
+	Groups   []string
+	Filter   gitlab.PipelineFilter
+	Workers  int
+}
+
+// Summary counts the outcome of a backfill
+type Summary struct {
+	Listed     int
+	Exported   int
+	Skipped    int
+	Unfinished int
+	Failed     int
+}
+
+func (s Summary) String() string {
+	return fmt.Sprintf("%d pipelines listed, %d exported, %d already exported, %d unfinished, %d failed",
+		s.Listed, s.Exported, s.Skipped, s.Unfinished, s.Failed)
+}
+
+type pipelineRef struct {
+	project    string
+	pipelineID int
+	key        string
+}
+
+// Backfiller exports the finished pipelines of a date range, remembering
+// exported pipelines in a checkpoint so an interrupted run can be resumed
+type Backfiller struct {
+	lister     gitlab.PipelineLister
+	checkpoint *checkpoint.Checkpoint
+	export     ExportFunc
+
+	mu      sync.Mutex
+	summary Summary
+	done    int
+	total   int
+}
+
+// New creates a backfiller
+func New(lister gitlab.PipelineLister, cp *checkpoint.Checkpoint, export ExportFunc) *Backfiller {
+	return &Backfiller{
+		lister:     lister,
+		checkpoint: cp,
+		export:     export,
+	}
+}
+
+// Run lists the pipelines matching opts and exports the finished ones that
+// were not exported before, with up to opts.Workers exports in flight
+func (b *Backfiller) Run(ctx context.Context, opts Options) (Summary, error) {
+	b.summary, b.done = Summary{}, 0
+
+	projects, err := b.lister.ResolveProjects(ctx, opts.Projects, opts.Groups)
+	if err != nil {
+		return Summary{}, err
+	}
+
+	var pending []pipelineRef
+	for _, project := range projects {
+		pipelines, err := b.lister.ListPipelines(ctx, project, opts.Filter)
+		if err != nil {
+			return Summary{}, fmt.Errorf("failed to list pipelines of project %s: %w", project, err)
+		}
+		b.summary.Listed += len(pipelines)
+
+		for _, pipeline := range pipelines {
+			if !gitlab.IsTerminalStatus(pipeline.Status) || pipeline.UpdatedAt == nil {
+				b.summary.Unfinished++
+				continue
+			}
+			key := checkpoint.PipelineKey(project, pipeline.ID, *pipeline.UpdatedAt)
+			if b.checkpoint.IsExported(key) {
+				b.summary.Skipped++
+				continue
+			}
+			pending = append(pending, pipelineRef{project: project, pipelineID: pipeline.ID, key: key})
+		}
+	}
+
+	b.total = len(pending)
+	fmt.Printf("Backfilling %d pipelines from %d projects\n", b.total, len(projects))
+
+	workers := opts.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	queue := make(chan pipelineRef)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ref := range queue {
+				b.exportPipeline(ctx, ref)
+			}
+		}()
+	}
+
+	for _, ref := range pending {
+		if ctx.Err() != nil {
+			break
+		}
+		queue <- ref
+	}
+	close(queue)
+	wg.Wait()
+
+	if err := b.checkpoint.Save(); err != nil {
+		return b.summary, fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+	return b.summary, ctx.Err()
+}
+
+func (b *Backfiller) exportPipeline(ctx context.Context, ref pipelineRef) {
+	err := b.export(ctx, ref.project, ref.pipelineID)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.done++
+	if err != nil {
+		b.summary.Failed++
+		log.Printf("[%d/%d] failed to export pipeline %d of project %s: %v", b.done, b.total, ref.pipelineID, ref.project, err)
+		return
+	}
+
+	b.summary.Exported++
+	b.checkpoint.MarkExported(ref.key)
+	fmt.Printf("[%d/%d] Exported pipeline %d of project %s\n", b.done, b.total, ref.pipelineID, ref.project)
+
+	// Saving after every export lets an interrupted run resume where it
+	// stopped
+	if err := b.checkpoint.Save(); err != nil {
+		log.Printf("failed to save checkpoint: %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package backfill
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/checkpoint"
+	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/config"
+	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/gitlab"
+)
+
+func TestRunExportsAndResumes(t *testing.T) {
+	var query map[string]string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v4/projects/10/pipelines" {
+			http.NotFound(w, r)
+			return
+		}
+		query = map[string]string{"ref": r.URL.Query().Get("ref"), "status": r.URL.Query().Get("status")}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode([]map[string]interface{}{
+			{"id": 1, "status": "success", "updated_at": "2026-01-01T10:00:00Z"},
+			{"id": 2, "status": "failed", "updated_at": "2026-01-02T10:00:00Z"},
+			{"id": 3, "status": "running", "updated_at": "2026-01-03T10:00:00Z"},
+			{"id": 4, "status": "success", "updated_at": "2026-01-04T10:00:00Z"},
+		})
+	}))
+	defer api.Close()
+
+	gitClient, err := gitlab.NewClient(&config.Config{ServerURL: api.URL})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	opts := Options{
+		Projects: []string{"10"},
+		Filter:   gitlab.PipelineFilter{UpdatedAfter: &since, Ref: "main"},
+		Workers:  2,
+	}
+
+	var mu sync.Mutex
+	var exported []int
+	failing := map[int]bool{4: true}
+	export := func(_ context.Context, _ string, pipelineID int) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if failing[pipelineID] {
+			return errors.New("export failed")
+		}
+		exported = append(exported, pipelineID)
+		return nil
+	}
+
+	cp, err := checkpoint.Load(path)
+	if err != nil {
+		t.Fatalf("checkpoint.Load failed: %v", err)
+	}
+	summary, err := New(gitClient, cp, export).Run(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	expected := Summary{Listed: 4, Exported: 2, Unfinished: 1, Failed: 1}
+	if summary != expected {
+		t.Errorf("expected summary %+v, got %+v", expected, summary)
+	}
+	if query["ref"] != "main" {
+		t.Errorf("expected ref filter to be sent, got %v", query)
+	}
+
+	// A second run resumes from the checkpoint and only retries the failure
+	failing = map[int]bool{}
+	cp, err = checkpoint.Load(path)
+	if err != nil {
+		t.Fatalf("checkpoint.Load failed: %v", err)
+	}
+	summary, err = New(gitClient, cp, export).Run(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	expected = Summary{Listed: 4, Exported: 1, Skipped: 2, Unfinished: 1}
+	if summary != expected {
+		t.Errorf("expected summary %+v, got %+v", expected, summary)
+	}
+
+	sort.Ints(exported)
+	if len(exported) != 3 || exported[0] != 1 || exported[1] != 2 || exported[2] != 4 {
+		t.Errorf("expected pipelines 1, 2 and 4 exported once, got %v", exported)
+	}
+}
@@ -0,0 +1,124 @@
+package checkpoint
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Checkpoint persists export progress in a local JSON file so that pipelines
+// are not exported twice across restarts
+type Checkpoint struct {
+	path string
+	mu   sync.Mutex
+
+	// Cursors holds, per project, the latest pipeline update time processed
+	Cursors map[string]time.Time `json:"cursors"`
+
+	// Exported holds the keys of exported pipelines and when they were exported
+	Exported map[string]time.Time `json:"exported"`
+}
+
+// PipelineKey identifies one completion of a pipeline. A retried job updates a
+// finished pipeline, so the key includes the update time.
+func PipelineKey(project string, pipelineID int, updatedAt time.Time) string {
+	return fmt.Sprintf("%s/%d/%s", project, pipelineID, updatedAt.UTC().Format(time.RFC3339))
+}
+
+// Load reads the checkpoint file at path. A missing file yields an empty
+// checkpoint that will be created on the first Save.
+func Load(path string) (*Checkpoint, error) {
+	c := &Checkpoint{
+		path:     path,
+		Cursors:  make(map[string]time.Time),
+		Exported: make(map[string]time.Time),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, err
+	}
+	if c.Cursors == nil {
+		c.Cursors = make(map[string]time.Time)
+	}
+	if c.Exported == nil {
+		c.Exported = make(map[string]time.Time)
+	}
+	return c, nil
+}
+
+// Cursor returns the latest pipeline update time processed for a project
+func (c *Checkpoint) Cursor(project string) (time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cursor, ok := c.Cursors[project]
+	return cursor, ok
+}
+
+// SetCursor records the latest pipeline update time processed for a project
+func (c *Checkpoint) SetCursor(project string, cursor time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Cursors[project] = cursor
+}
+
+// IsExported reports whether the pipeline identified by key was exported
+func (c *Checkpoint) IsExported(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.Exported[key]
+	return ok
+}
+
+// MarkExported records the pipeline identified by key as exported
+func (c *Checkpoint) MarkExported(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Exported[key] = time.Now()
+}
+
+// Prune forgets exported pipelines recorded more than retention ago
+func (c *Checkpoint) Prune(retention time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, at := range c.Exported {
+		if time.Since(at) > retention {
+			delete(c.Exported, key)
+		}
+	}
+}
+
+// Save writes the checkpoint file atomically
+func (c *Checkpoint) Save() error {
+	c.mu.Lock()
+	data, err := json.MarshalIndent(c, "", "  ")
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path)
+}
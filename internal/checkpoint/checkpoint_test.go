@@ -0,0 +1,52 @@
+package checkpoint
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCheckpointRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+
+	c, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load of missing file should succeed: %v", err)
+	}
+	if c.IsExported("10/20") {
+		t.Error("empty checkpoint should have no exported pipelines")
+	}
+
+	cursor := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	c.SetCursor("10", cursor)
+	c.MarkExported("10/20")
+	if err := c.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	reloaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if !reloaded.IsExported("10/20") {
+		t.Error("exported pipeline should survive a reload")
+	}
+	if got, ok := reloaded.Cursor("10"); !ok || !got.Equal(cursor) {
+		t.Errorf("cursor should survive a reload, got %v", got)
+	}
+}
+
+func TestCheckpointPrune(t *testing.T) {
+	c, err := Load(filepath.Join(t.TempDir(), "checkpoint.json"))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	c.Exported["old"] = time.Now().Add(-48 * time.Hour)
+	c.MarkExported("new")
+	c.Prune(24 * time.Hour)
+
+	if c.IsExported("old") || !c.IsExported("new") {
+		t.Errorf("prune should only forget old entries, got %v", c.Exported)
+	}
+}
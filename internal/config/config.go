@@ -1,6 +1,19 @@
 package config
 
-import "os"
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
 
 // Config holds all configuration for the exporter
 type Config struct {
@@ -8,27 +21,324 @@ type Config struct {
 	Protocol string
 	Endpoint string
 
+	// Signal-specific OTLP endpoints, used as-is instead of Endpoint
+	TracesEndpoint  string
+	LogsEndpoint    string
+	MetricsEndpoint string
+
+	// OTLPFile is the file traces are appended to with the file protocol
+	OTLPFile string
+
+	// OTLP connection settings. Headers is a comma-separated list of
+	// key=value pairs with URL-encoded values, Timeout is in milliseconds.
+	Headers           string
+	Timeout           int
+	Compression       string
+	Certificate       string
+	ClientCertificate string
+	ClientKey         string
+	Insecure          bool
+
+	// Resource settings. ResourceAttributes has the same format as Headers.
+	ServiceName        string
+	ResourceAttributes string
+
+	// DeterministicIDs derives trace and span IDs from GitLab IDs
+	DeterministicIDs bool
+
 	// GitLab Configuration
 	Token      string
+	TokenType  string
 	ServerURL  string
 	ProjectID  string
 	PipelineID string
 
+	// CaptureDir holds saved GitLab API responses. The capture command
+	// writes it, and export reads pipelines from it instead of the API.
+	CaptureDir string
+
+	// Pipeline metadata that GitLab CI provides as predefined variables.
+	// Outside CI they can be given as flags or in the config file.
+	ProjectNamespace string
+	ProjectName      string
+	ProjectURL       string
+	PipelineName     string
+	PipelineSource   string
+	CommitRefName    string
+	CommitSHA        string
+	CommitTag        string
+	ParentPipelineID string
+	ParentProjectID  string
+	TraceParent      string
+
+	// Job pagination settings
+	JobsPerPage int
+	MaxJobs     int
+
+	// GitLab API request settings. Requests failing with 429, 5xx or a
+	// network error are retried up to GitLabRetries times, waiting at most
+	// GitLabRetryWaitMax in between. GitLabTimeout bounds each request and
+	// ExportTimeout the export of a whole pipeline (0 for no deadline).
+	GitLabRetries      int
+	GitLabRetryWaitMax time.Duration
+	GitLabTimeout      time.Duration
+	ExportTimeout      time.Duration
+
+	// RetriedJobs fetches every attempt of retried jobs, not only the last
+	RetriedJobs bool
+
+	// UnstartedJobs is what becomes of jobs and bridges that never started,
+	// such as skipped jobs and manual jobs nobody played: drop, span or event
+	UnstartedJobs string
+
+	// JobNeeds links job spans to the spans of the jobs they need, read
+	// from the GraphQL API, which takes a private token
+	JobNeeds bool
+
+	// StageSpans groups job spans under one span per stage
+	StageSpans bool
+
+	// JobSections downloads job logs and exports their sections as spans
+	JobSections bool
+
+	// Job log export settings
+	ExportLogs   bool
+	LogMaxBytes  int
+	LogTailLines int
+
+	// ExportMetrics records pipeline and job metrics next to the traces
+	ExportMetrics bool
+
+	// Downstream pipeline settings
+	ExportDownstream   bool
+	DownstreamMaxDepth int
+
+	// Webhook server settings
+	ListenAddr     string
+	WebhookSecret  string
+	WebhookWorkers int
+
+	// Polling settings
+	PollProjects   []string
+	PollGroups     []string
+	PollInterval   time.Duration
+	PollLookback   time.Duration
+	CheckpointFile string
+
+	// Dry-run settings. DryRunFormat is text or json, DryRunOutput a file
+	// path or empty for standard output.
+	DryRun       bool
+	DryRunFormat string
+	DryRunOutput string
+
 	// Debug settings
 	Debug bool
 }
 
-// Load creates a new configuration from environment variables
-func Load() *Config {
+// setting describes one configuration value. The name is used as the flag
+// name and, with dashes replaced by underscores, as the config file key.
+type setting struct {
+	name  string
+	env   []string
+	usage string
+	field func(c *Config) interface{}
+}
+
+// settings lists every configuration value in the order they are documented
+var settings = []setting{
+	{"otlp-protocol", []string{"OTEL_EXPORTER_OTLP_PROTOCOL"}, "OTLP protocol: http/protobuf, grpc, stdout or file", func(c *Config) interface{} { return &c.Protocol }},
+	{"otlp-endpoint", []string{"OTEL_EXPORTER_OTLP_ENDPOINT"}, "OTLP endpoint as host:port or base URL (default depends on the protocol)", func(c *Config) interface{} { return &c.Endpoint }},
+	{"otlp-traces-endpoint", []string{"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"}, "OTLP endpoint for traces, used as-is", func(c *Config) interface{} { return &c.TracesEndpoint }},
+	{"otlp-logs-endpoint", []string{"OTEL_EXPORTER_OTLP_LOGS_ENDPOINT"}, "OTLP endpoint for logs, used as-is", func(c *Config) interface{} { return &c.LogsEndpoint }},
+	{"otlp-metrics-endpoint", []string{"OTEL_EXPORTER_OTLP_METRICS_ENDPOINT"}, "OTLP endpoint for metrics, used as-is", func(c *Config) interface{} { return &c.MetricsEndpoint }},
+	{"otlp-file", []string{"OTLP_FILE"}, "file that OTLP/JSON traces are appended to with the file protocol", func(c *Config) interface{} { return &c.OTLPFile }},
+	{"otlp-timeout", []string{"OTEL_EXPORTER_OTLP_TIMEOUT"}, "timeout of each export in milliseconds", func(c *Config) interface{} { return &c.Timeout }},
+	{"otlp-compression", []string{"OTEL_EXPORTER_OTLP_COMPRESSION"}, "compression of exports: gzip or none", func(c *Config) interface{} { return &c.Compression }},
+	{"otlp-headers", []string{"OTEL_EXPORTER_OTLP_HEADERS"}, "headers sent with every export, as key=value pairs separated by commas", func(c *Config) interface{} { return &c.Headers }},
+	{"otlp-certificate", []string{"OTEL_EXPORTER_OTLP_CERTIFICATE"}, "PEM file of the CA certificates verifying the collector", func(c *Config) interface{} { return &c.Certificate }},
+	{"otlp-client-certificate", []string{"OTEL_EXPORTER_OTLP_CLIENT_CERTIFICATE"}, "PEM file of the client certificate for mTLS", func(c *Config) interface{} { return &c.ClientCertificate }},
+	{"otlp-client-key", []string{"OTEL_EXPORTER_OTLP_CLIENT_KEY"}, "PEM file of the client private key for mTLS", func(c *Config) interface{} { return &c.ClientKey }},
+	{"otlp-insecure", []string{"OTEL_EXPORTER_OTLP_INSECURE"}, "connect to the collector without TLS", func(c *Config) interface{} { return &c.Insecure }},
+	{"service-name", []string{"OTEL_SERVICE_NAME"}, "service name (default <namespace>/<project>, or gitlab-otel-exporter outside of a pipeline)", func(c *Config) interface{} { return &c.ServiceName }},
+	{"resource-attributes", []string{"OTEL_RESOURCE_ATTRIBUTES"}, "resource attributes, as key=value pairs separated by commas", func(c *Config) interface{} { return &c.ResourceAttributes }},
+	{"deterministic-ids", []string{"DETERMINISTIC_IDS"}, "derive trace and span IDs from GitLab IDs", func(c *Config) interface{} { return &c.DeterministicIDs }},
+
+	{"gitlab-token", []string{"GITLAB_TOKEN"}, "GitLab token", func(c *Config) interface{} { return &c.Token }},
+	{"gitlab-token-type", []string{"GITLAB_TOKEN_TYPE"}, "GitLab token type: job or private", func(c *Config) interface{} { return &c.TokenType }},
+	{"gitlab-server-url", []string{"GITLAB_SERVER_URL", "CI_SERVER_URL"}, "GitLab server URL", func(c *Config) interface{} { return &c.ServerURL }},
+	{"project-id", []string{"CI_PROJECT_ID"}, "ID or path of the project to export", func(c *Config) interface{} { return &c.ProjectID }},
+	{"pipeline-id", []string{"CI_PIPELINE_ID"}, "ID of the pipeline to export", func(c *Config) interface{} { return &c.PipelineID }},
+	{"capture-dir", []string{"CAPTURE_DIR"}, "directory of GitLab API responses, written by capture and read by export instead of the API", func(c *Config) interface{} { return &c.CaptureDir }},
+
+	{"project-namespace", []string{"CI_PROJECT_NAMESPACE"}, "project namespace, used in the service and pipeline span names", func(c *Config) interface{} { return &c.ProjectNamespace }},
+	{"project-name", []string{"CI_PROJECT_NAME"}, "project name, used in the service and pipeline span names", func(c *Config) interface{} { return &c.ProjectName }},
+	{"project-url", []string{"CI_PROJECT_URL"}, "project URL", func(c *Config) interface{} { return &c.ProjectURL }},
+	{"pipeline-name", []string{"CI_PIPELINE_NAME"}, "pipeline name", func(c *Config) interface{} { return &c.PipelineName }},
+	{"pipeline-source", []string{"CI_PIPELINE_SOURCE"}, "how the pipeline was triggered, e.g. push or pipeline", func(c *Config) interface{} { return &c.PipelineSource }},
+	{"commit-ref-name", []string{"CI_COMMIT_REF_NAME"}, "branch or tag the pipeline runs for", func(c *Config) interface{} { return &c.CommitRefName }},
+	{"commit-sha", []string{"CI_COMMIT_SHA"}, "commit the pipeline runs for, used as service version", func(c *Config) interface{} { return &c.CommitSHA }},
+	{"commit-tag", []string{"CI_COMMIT_TAG"}, "tag the pipeline runs for, if any", func(c *Config) interface{} { return &c.CommitTag }},
+	{"parent-pipeline-id", []string{"CI_PARENT_PIPELINE_ID"}, "ID of the upstream pipeline", func(c *Config) interface{} { return &c.ParentPipelineID }},
+	{"parent-project-id", []string{"CI_PARENT_PROJECT_ID"}, "project ID of the upstream pipeline", func(c *Config) interface{} { return &c.ParentProjectID }},
+	{"traceparent", []string{"TRACEPARENT"}, "W3C trace context of the upstream pipeline", func(c *Config) interface{} { return &c.TraceParent }},
+
+	{"gitlab-jobs-per-page", []string{"GITLAB_JOBS_PER_PAGE"}, "jobs fetched per API request", func(c *Config) interface{} { return &c.JobsPerPage }},
+	{"gitlab-max-jobs", []string{"GITLAB_MAX_JOBS"}, "maximum jobs fetched per pipeline", func(c *Config) interface{} { return &c.MaxJobs }},
+	{"gitlab-retries", []string{"GITLAB_RETRIES"}, "retries of a GitLab API request failing with 429, 5xx or a network error", func(c *Config) interface{} { return &c.GitLabRetries }},
+	{"gitlab-retry-wait-max", []string{"GITLAB_RETRY_WAIT_MAX"}, "longest wait before retrying a GitLab API request; a longer Retry-After or RateLimit-Reset fails the request", func(c *Config) interface{} { return &c.GitLabRetryWaitMax }},
+	{"gitlab-timeout", []string{"GITLAB_TIMEOUT"}, "timeout of each GitLab API request", func(c *Config) interface{} { return &c.GitLabTimeout }},
+	{"export-timeout", []string{"EXPORT_TIMEOUT"}, "deadline of each pipeline export (0 for none)", func(c *Config) interface{} { return &c.ExportTimeout }},
+	{"retried-jobs", []string{"RETRIED_JOBS"}, "export every attempt of retried jobs, not only the last", func(c *Config) interface{} { return &c.RetriedJobs }},
+	{"job-needs", []string{"JOB_NEEDS"}, "link job spans to the jobs they need (needs:), read from the GraphQL API with a private token", func(c *Config) interface{} { return &c.JobNeeds }},
+	{"unstarted-jobs", []string{"UNSTARTED_JOBS"}, "jobs and bridges that never started, e.g. skipped and manual jobs: drop, span (zero-length spans) or event (events on the pipeline span)", func(c *Config) interface{} { return &c.UnstartedJobs }},
+	{"stage-spans", []string{"STAGE_SPANS"}, "group job spans under one span per stage", func(c *Config) interface{} { return &c.StageSpans }},
+	{"job-sections", []string{"JOB_SECTIONS"}, "export job log sections as spans", func(c *Config) interface{} { return &c.JobSections }},
+	{"export-logs", []string{"EXPORT_LOGS"}, "export job logs as log records", func(c *Config) interface{} { return &c.ExportLogs }},
+	{"log-max-bytes", []string{"LOG_MAX_BYTES"}, "maximum bytes of each job log exported, 0 for no limit", func(c *Config) interface{} { return &c.LogMaxBytes }},
+	{"log-tail-lines", []string{"LOG_TAIL_LINES"}, "only export the last lines of each job log (0 for all)", func(c *Config) interface{} { return &c.LogTailLines }},
+	{"export-metrics", []string{"EXPORT_METRICS"}, "record pipeline and job metrics", func(c *Config) interface{} { return &c.ExportMetrics }},
+	{"export-downstream", []string{"EXPORT_DOWNSTREAM"}, "export downstream pipelines into the same trace", func(c *Config) interface{} { return &c.ExportDownstream }},
+	{"export-downstream-max-depth", []string{"EXPORT_DOWNSTREAM_MAX_DEPTH"}, "maximum downstream pipeline depth", func(c *Config) interface{} { return &c.DownstreamMaxDepth }},
+
+	{"webhook-listen-addr", []string{"WEBHOOK_LISTEN_ADDR"}, "address the webhook server listens on", func(c *Config) interface{} { return &c.ListenAddr }},
+	{"webhook-secret", []string{"WEBHOOK_SECRET"}, "secret token of the webhook", func(c *Config) interface{} { return &c.WebhookSecret }},
+	{"webhook-workers", []string{"WEBHOOK_WORKERS"}, "concurrent webhook exports", func(c *Config) interface{} { return &c.WebhookWorkers }},
+
+	{"poll-projects", []string{"POLL_PROJECTS"}, "comma-separated projects to poll", func(c *Config) interface{} { return &c.PollProjects }},
+	{"poll-groups", []string{"POLL_GROUPS"}, "comma-separated groups to poll", func(c *Config) interface{} { return &c.PollGroups }},
+	{"poll-interval", []string{"POLL_INTERVAL"}, "time between polls", func(c *Config) interface{} { return &c.PollInterval }},
+	{"poll-lookback", []string{"POLL_LOOKBACK"}, "window read on the first poll of a project", func(c *Config) interface{} { return &c.PollLookback }},
+	{"checkpoint-file", []string{"CHECKPOINT_FILE"}, "file recording exported pipelines", func(c *Config) interface{} { return &c.CheckpointFile }},
+
+	{"dry-run", []string{"DRY_RUN"}, "print the trace tree instead of exporting it", func(c *Config) interface{} { return &c.DryRun }},
+	{"dry-run-format", []string{"DRY_RUN_FORMAT"}, "format of the dry-run trace tree: text or json", func(c *Config) interface{} { return &c.DryRunFormat }},
+	{"dry-run-output", []string{"DRY_RUN_OUTPUT"}, "file the dry-run trace tree is written to (default standard output)", func(c *Config) interface{} { return &c.DryRunOutput }},
+
+	{"debug", []string{"DEBUG"}, "print debug output", func(c *Config) interface{} { return &c.Debug }},
+}
+
+// Default returns the configuration used when nothing else is set
+func Default() *Config {
 	return &Config{
-		Protocol:   getEnv("OTEL_EXPORTER_OTLP_PROTOCOL", "http"),
-		Endpoint:   getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", ""),
-		Token:      os.Getenv("GITLAB_TOKEN"),
-		ServerURL:  getEnv("GITLAB_SERVER_URL", os.Getenv("CI_SERVER_URL")),
-		ProjectID:  os.Getenv("CI_PROJECT_ID"),
-		PipelineID: os.Getenv("CI_PIPELINE_ID"),
-		Debug:      os.Getenv("DEBUG") == "true",
+		Protocol:           "http/protobuf",
+		OTLPFile:           "otlp-traces.jsonl",
+		Timeout:            10000,
+		Insecure:           true,
+		TokenType:          "job",
+		JobsPerPage:        100,
+		MaxJobs:            5000,
+		GitLabRetries:      5,
+		GitLabRetryWaitMax: 30 * time.Second,
+		GitLabTimeout:      30 * time.Second,
+		ExportTimeout:      5 * time.Minute,
+		RetriedJobs:        true,
+		UnstartedJobs:      "drop",
+		LogMaxBytes:        1024 * 1024,
+		DownstreamMaxDepth: 3,
+		ListenAddr:         ":8080",
+		WebhookWorkers:     4,
+		PollInterval:       time.Minute,
+		PollLookback:       time.Hour,
+		CheckpointFile:     "gitlab-otel-exporter.checkpoint.json",
+		DryRunFormat:       "text",
+	}
+}
+
+// Load creates a new configuration from, in increasing precedence, the
+// defaults, environment variables, a YAML or TOML config file and the flags
+// in args. The config file is named by the -config flag or CONFIG_FILE. It
+// takes precedence over the environment so that its settings hold inside
+// GitLab CI, where the CI_* variables are always set.
+//
+// The configuration flags are added to fs, so callers can register flags of
+// their own on it before calling Load.
+func Load(fs *flag.FlagSet, args []string) (*Config, error) {
+	cfg := Default()
+
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "YAML or TOML config file")
+	for _, s := range settings {
+		bindFlag(fs, s, cfg)
+	}
+
+	// The config file is named by a flag, so flags are parsed once to find
+	// it and again on top of the environment and file
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.loadEnv(); err != nil {
+		return nil, err
+	}
+	if *configFile != "" {
+		if err := cfg.loadFile(*configFile); err != nil {
+			return nil, err
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// loadFile applies the settings present in a YAML or TOML file, chosen by
+// the file extension
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	values := map[string]interface{}{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		err = toml.Unmarshal(data, &values)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	default:
+		return fmt.Errorf("config file %s: unsupported format, expected .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	known := map[string]bool{}
+	var errs []error
+	for _, s := range settings {
+		key := fileKey(s.name)
+		known[key] = true
+		value, ok := values[key]
+		if !ok {
+			continue
+		}
+		if err := setField(s.field(c), fileValue(value)); err != nil {
+			errs = append(errs, fmt.Errorf("config file %s: %s: %w", path, key, err))
+		}
+	}
+	for key := range values {
+		if !known[key] {
+			errs = append(errs, fmt.Errorf("config file %s: unknown setting %s", path, key))
+		}
 	}
+	return errors.Join(errs...)
+}
+
+// loadEnv applies the settings whose environment variables are set
+func (c *Config) loadEnv() error {
+	var errs []error
+	for _, s := range settings {
+		for _, key := range s.env {
+			value := os.Getenv(key)
+			if value == "" {
+				continue
+			}
+			if err := setField(s.field(c), value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", key, err))
+			}
+			break
+		}
+	}
+	return errors.Join(errs...)
 }
 
 // GetEndpoint returns the configured endpoint or default for protocol
@@ -39,9 +349,84 @@ func (c *Config) GetEndpoint() string {
 	return getDefaultEndpoint(c.Protocol)
 }
 
+// SignalEndpoint returns the endpoint for a signal (traces, logs or
+// metrics). As in the OpenTelemetry SDKs, a signal-specific endpoint is used
+// as-is, while the signal path is appended to a base URL for HTTP. With the
+// file protocol the endpoint is the output file.
+func (c *Config) SignalEndpoint(signal string) string {
+	if c.Protocol == "file" {
+		return c.OTLPFile
+	}
+
+	specific := map[string]string{
+		"traces":  c.TracesEndpoint,
+		"logs":    c.LogsEndpoint,
+		"metrics": c.MetricsEndpoint,
+	}[signal]
+	if specific != "" {
+		return specific
+	}
+
+	endpoint := c.GetEndpoint()
+	if IsHTTPProtocol(c.Protocol) && strings.Contains(endpoint, "://") {
+		return strings.TrimSuffix(endpoint, "/") + "/v1/" + signal
+	}
+	return endpoint
+}
+
+// UsesTLS reports whether the collector connection uses TLS. As in the
+// OpenTelemetry SDKs the scheme of an endpoint URL decides, otherwise
+// Insecure does.
+func (c *Config) UsesTLS() bool {
+	endpoint := c.SignalEndpoint("traces")
+	switch {
+	case strings.HasPrefix(endpoint, "https://"):
+		return true
+	case strings.HasPrefix(endpoint, "http://"):
+		return false
+	default:
+		return !c.Insecure
+	}
+}
+
+// IsHTTPProtocol reports whether protocol selects OTLP over HTTP with
+// protobuf encoding. "http" is kept as an alias of the spec value.
+func IsHTTPProtocol(protocol string) bool {
+	return protocol == "http" || protocol == "http/protobuf"
+}
+
+// OTLPHeaders parses the configured OTLP headers
+func (c *Config) OTLPHeaders() (map[string]string, error) {
+	return parseKeyValues(c.Headers)
+}
+
+// ResourceAttributeMap parses the configured resource attributes
+func (c *Config) ResourceAttributeMap() (map[string]string, error) {
+	return parseKeyValues(c.ResourceAttributes)
+}
+
+// parseKeyValues parses comma-separated key=value pairs with URL-encoded
+// values, the format of OTEL_EXPORTER_OTLP_HEADERS and OTEL_RESOURCE_ATTRIBUTES
+func parseKeyValues(value string) (map[string]string, error) {
+	values := map[string]string{}
+	for _, pair := range splitList(value) {
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid pair %q, expected key=value", pair)
+		}
+		decoded, err := url.QueryUnescape(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of %s: %w", key, err)
+		}
+		values[key] = decoded
+	}
+	return values, nil
+}
+
 func getDefaultEndpoint(protocol string) string {
 	switch protocol {
-	case "http":
+	case "http", "http/protobuf":
 		return "localhost:4318"
 	case "grpc":
 		return "localhost:4317"
@@ -52,9 +437,92 @@ func getDefaultEndpoint(protocol string) string {
 	}
 }
 
-func getEnv(key, fallback string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+func fileKey(name string) string {
+	return strings.ReplaceAll(name, "-", "_")
+}
+
+// fileValue converts a value decoded from a config file to the string form
+// used by environment variables and flags
+func fileValue(value interface{}) string {
+	if items, ok := value.([]interface{}); ok {
+		parts := make([]string, len(items))
+		for i, item := range items {
+			parts[i] = fmt.Sprint(item)
+		}
+		return strings.Join(parts, ",")
+	}
+	return fmt.Sprint(value)
+}
+
+// setField parses value into the Config field pointed to by field
+func setField(field interface{}, value string) error {
+	switch f := field.(type) {
+	case *string:
+		*f = value
+	case *bool:
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		*f = v
+	case *int:
+		v, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		*f = v
+	case *time.Duration:
+		v, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", value)
+		}
+		*f = v
+	case *[]string:
+		*f = splitList(value)
+	default:
+		return fmt.Errorf("unsupported setting type %T", field)
 	}
-	return fallback
+	return nil
+}
+
+// splitList splits a comma-separated value, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// bindFlag registers the flag of a setting, bound to its field in cfg
+func bindFlag(fs *flag.FlagSet, s setting, cfg *Config) {
+	switch f := s.field(cfg).(type) {
+	case *string:
+		fs.StringVar(f, s.name, *f, s.usage)
+	case *bool:
+		fs.BoolVar(f, s.name, *f, s.usage)
+	case *int:
+		fs.IntVar(f, s.name, *f, s.usage)
+	case *time.Duration:
+		fs.DurationVar(f, s.name, *f, s.usage)
+	case *[]string:
+		fs.Var((*listValue)(f), s.name, s.usage)
+	}
+}
+
+// listValue adapts a comma-separated list setting to flag.Value
+type listValue []string
+
+func (v *listValue) String() string {
+	if v == nil {
+		return ""
+	}
+	return strings.Join(*v, ",")
+}
+
+func (v *listValue) Set(value string) error {
+	*v = splitList(value)
+	return nil
 }
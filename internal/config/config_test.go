@@ -1,8 +1,12 @@
 package config
 
 import (
+	"flag"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestLoad(t *testing.T) {
@@ -11,10 +15,13 @@ func TestLoad(t *testing.T) {
 	_ = os.Unsetenv("OTEL_EXPORTER_OTLP_ENDPOINT")
 	_ = os.Unsetenv("DEBUG")
 
-	cfg := Load()
+	cfg, err := Load(newFlagSet(), nil)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
 
-	if cfg.Protocol != "http" {
-		t.Errorf("expected default protocol 'http', got %s", cfg.Protocol)
+	if cfg.Protocol != "http/protobuf" {
+		t.Errorf("expected default protocol 'http/protobuf', got %s", cfg.Protocol)
 	}
 	if cfg.Debug != false {
 		t.Errorf("expected debug false by default, got %v", cfg.Debug)
@@ -46,24 +53,202 @@ func TestGetEndpoint(t *testing.T) {
 	}
 }
 
-func TestGetEnv(t *testing.T) {
+func newFlagSet() *flag.FlagSet {
+	return flag.NewFlagSet("test", flag.ContinueOnError)
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.yaml")
+	content := "otlp_protocol: grpc\nproject_id: from-file\npipeline_id: \"1\"\nstage_spans: true\npoll_projects: [group/a, 42]\n"
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_ = os.Setenv("CI_PROJECT_ID", "from-env")
+	_ = os.Setenv("CI_PIPELINE_ID", "2")
+	_ = os.Setenv("GITLAB_JOBS_PER_PAGE", "50")
+	defer func() {
+		_ = os.Unsetenv("CI_PROJECT_ID")
+		_ = os.Unsetenv("CI_PIPELINE_ID")
+		_ = os.Unsetenv("GITLAB_JOBS_PER_PAGE")
+	}()
+
+	cfg, err := Load(newFlagSet(), []string{"-config", file, "-pipeline-id", "3"})
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if cfg.Protocol != "grpc" {
+		t.Errorf("expected protocol from file, got %s", cfg.Protocol)
+	}
+	if cfg.ProjectID != "from-file" {
+		t.Errorf("expected file to override env, got %s", cfg.ProjectID)
+	}
+	if cfg.PipelineID != "3" {
+		t.Errorf("expected flag to override file and env, got %s", cfg.PipelineID)
+	}
+	if cfg.JobsPerPage != 50 {
+		t.Errorf("expected jobs per page from env, got %d", cfg.JobsPerPage)
+	}
+	if !cfg.StageSpans {
+		t.Error("expected stage spans enabled from file")
+	}
+	if len(cfg.PollProjects) != 2 || cfg.PollProjects[1] != "42" {
+		t.Errorf("expected poll projects from file list, got %v", cfg.PollProjects)
+	}
+	if cfg.MaxJobs != 5000 {
+		t.Errorf("expected default max jobs 5000, got %d", cfg.MaxJobs)
+	}
+}
+
+func TestLoadTOMLFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.toml")
+	content := "otlp_endpoint = \"collector:4318\"\nlog_tail_lines = 200\npoll_interval = \"30s\"\n"
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(newFlagSet(), []string{"-config", file})
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Endpoint != "collector:4318" || cfg.LogTailLines != 200 || cfg.PollInterval != 30*time.Second {
+		t.Errorf("unexpected config from TOML file: %+v", cfg)
+	}
+}
+
+func TestLoadReportsInvalidSettings(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(file, []byte("gitlab_max_jobs: many\nunknown_key: 1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := Load(newFlagSet(), []string{"-config", file})
+	if err == nil {
+		t.Fatal("expected an error for invalid settings")
+	}
+	for _, want := range []string{"gitlab_max_jobs", "unknown_key"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to mention %s, got %v", want, err)
+		}
+	}
+
+	_ = os.Setenv("GITLAB_MAX_JOBS", "many")
+	defer func() { _ = os.Unsetenv("GITLAB_MAX_JOBS") }()
+	if _, err := Load(newFlagSet(), nil); err == nil || !strings.Contains(err.Error(), "GITLAB_MAX_JOBS") {
+		t.Errorf("expected an error naming GITLAB_MAX_JOBS, got %v", err)
+	}
+}
+
+func TestLoadServerURLFallback(t *testing.T) {
+	_ = os.Setenv("CI_SERVER_URL", "https://ci.example.com")
+	defer func() { _ = os.Unsetenv("CI_SERVER_URL") }()
+
+	cfg, err := Load(newFlagSet(), nil)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.ServerURL != "https://ci.example.com" {
+		t.Errorf("expected CI_SERVER_URL fallback, got %s", cfg.ServerURL)
+	}
+
+	_ = os.Setenv("GITLAB_SERVER_URL", "https://gitlab.example.com")
+	defer func() { _ = os.Unsetenv("GITLAB_SERVER_URL") }()
+
+	cfg, err = Load(newFlagSet(), nil)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.ServerURL != "https://gitlab.example.com" {
+		t.Errorf("expected GITLAB_SERVER_URL to take precedence, got %s", cfg.ServerURL)
+	}
+}
+
+func TestLoadJobPagination(t *testing.T) {
+	_ = os.Setenv("GITLAB_JOBS_PER_PAGE", "50")
+	_ = os.Unsetenv("GITLAB_MAX_JOBS")
+	defer func() { _ = os.Unsetenv("GITLAB_JOBS_PER_PAGE") }()
+
+	cfg, err := Load(newFlagSet(), nil)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if cfg.JobsPerPage != 50 {
+		t.Errorf("expected jobs per page 50, got %d", cfg.JobsPerPage)
+	}
+	if cfg.MaxJobs != 5000 {
+		t.Errorf("expected default max jobs 5000, got %d", cfg.MaxJobs)
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	got := splitList(" group/a, 42 ,,group/b ")
+	want := []string{"group/a", "42", "group/b"}
+	if len(got) != len(want) {
+		t.Fatalf("splitList() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("splitList()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	if got := splitList(""); len(got) != 0 {
+		t.Errorf("splitList() of empty value = %v, want empty", got)
+	}
+}
+
+func TestOTLPHeaders(t *testing.T) {
+	cfg := &Config{Headers: "x-honeycomb-team=abc123, Authorization=Bearer%20token"}
+
+	headers, err := cfg.OTLPHeaders()
+	if err != nil {
+		t.Fatalf("OTLPHeaders failed: %v", err)
+	}
+	if headers["x-honeycomb-team"] != "abc123" {
+		t.Errorf("expected x-honeycomb-team=abc123, got %q", headers["x-honeycomb-team"])
+	}
+	if headers["Authorization"] != "Bearer token" {
+		t.Errorf("expected URL-decoded Authorization header, got %q", headers["Authorization"])
+	}
+
+	cfg.Headers = "missing-value"
+	if _, err := cfg.OTLPHeaders(); err == nil {
+		t.Error("expected an error for a header without value")
+	}
+}
+
+func TestSignalEndpoint(t *testing.T) {
 	tests := []struct {
-		key      string
-		fallback string
-		envValue string
-		want     string
+		cfg    Config
+		signal string
+		want   string
 	}{
-		{"TEST_KEY", "default", "", "default"},
-		{"TEST_KEY", "default", "custom", "custom"},
+		{Config{Protocol: "http"}, "traces", "localhost:4318"},
+		{Config{Protocol: "http/protobuf", Endpoint: "collector:4318"}, "logs", "collector:4318"},
+		{Config{Protocol: "http/protobuf", Endpoint: "https://collector.example.com/otlp/"}, "traces", "https://collector.example.com/otlp/v1/traces"},
+		{Config{Protocol: "grpc", Endpoint: "https://collector.example.com:4317"}, "metrics", "https://collector.example.com:4317"},
+		{Config{Protocol: "http", Endpoint: "https://collector.example.com", TracesEndpoint: "https://traces.example.com/ingest"}, "traces", "https://traces.example.com/ingest"},
+		{Config{Protocol: "http", Endpoint: "https://collector.example.com", TracesEndpoint: "https://traces.example.com/ingest"}, "logs", "https://collector.example.com/v1/logs"},
 	}
 
 	for _, tt := range tests {
-		if tt.envValue != "" {
-			_ = os.Setenv(tt.key, tt.envValue)
-			defer func() { _ = os.Unsetenv(tt.key) }()
-		}
-		if got := getEnv(tt.key, tt.fallback); got != tt.want {
-			t.Errorf("getEnv(%q, %q) = %q, want %q", tt.key, tt.fallback, got, tt.want)
+		if got := tt.cfg.SignalEndpoint(tt.signal); got != tt.want {
+			t.Errorf("SignalEndpoint(%s) with %+v = %s, want %s", tt.signal, tt.cfg, got, tt.want)
 		}
 	}
 }
+
+func TestUsesTLS(t *testing.T) {
+	if (&Config{Protocol: "http", Insecure: true}).UsesTLS() {
+		t.Error("host:port endpoint with insecure should not use TLS")
+	}
+	if !(&Config{Protocol: "http", Insecure: true, Endpoint: "https://collector.example.com"}).UsesTLS() {
+		t.Error("https endpoint should use TLS")
+	}
+	if (&Config{Protocol: "grpc", Endpoint: "http://collector:4317"}).UsesTLS() {
+		t.Error("http endpoint should not use TLS")
+	}
+}
@@ -0,0 +1,226 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// traceParentRegex matches a W3C traceparent header value
+var traceParentRegex = regexp.MustCompile(`^[0-9a-f]{2}-[0-9a-f]{32}-[0-9a-f]{16}-[0-9a-f]{2}$`)
+
+// projectPathRegex matches a project path such as group/subgroup/project
+var projectPathRegex = regexp.MustCompile(`^[\w.-]+(/[\w.-]+)+$`)
+
+// validateOTLP checks the settings of the connection to the collector
+func (c *Config) validateOTLP(v *validator) {
+	endpoints := []struct{ name, value string }{
+		{"otlp-endpoint", c.Endpoint},
+		{"otlp-traces-endpoint", c.TracesEndpoint},
+		{"otlp-logs-endpoint", c.LogsEndpoint},
+		{"otlp-metrics-endpoint", c.MetricsEndpoint},
+	}
+	switch c.Protocol {
+	case "http", "http/protobuf", "grpc":
+		for _, endpoint := range endpoints {
+			if endpoint.value != "" {
+				v.check(isEndpoint(endpoint.value), endpoint.name, "must be host:port or an http or https URL, got %q", endpoint.value)
+			}
+		}
+	case "stdout", "console":
+		for _, endpoint := range endpoints {
+			v.check(endpoint.value == "", endpoint.name, "is set to %q but protocol %s writes to standard output; unset it or change the protocol", endpoint.value, c.Protocol)
+		}
+	case "file":
+		for _, endpoint := range endpoints {
+			v.check(endpoint.value == "", endpoint.name, "is set to %q but protocol file writes to otlp-file (%s); unset it or change the protocol", endpoint.value, describe("otlp-file"))
+		}
+		v.check(c.OTLPFile != "", "otlp-file", "is required with protocol file")
+		v.check(!c.ExportLogs, "export-logs", "is not supported with protocol file, which writes traces only")
+		v.check(!c.ExportMetrics, "export-metrics", "is not supported with protocol file, which writes traces only")
+	case "http/json":
+		v.problem("otlp-protocol", "http/json is not supported by the OpenTelemetry Go SDK, use http/protobuf or grpc")
+	default:
+		v.problem("otlp-protocol", "unknown protocol %q, expected http/protobuf, grpc, stdout or file", c.Protocol)
+	}
+	v.check(c.Timeout > 0, "otlp-timeout", "must be a positive number of milliseconds, got %d", c.Timeout)
+	v.check(c.Compression == "" || c.Compression == "none" || c.Compression == "gzip", "otlp-compression", "must be gzip or none, got %q", c.Compression)
+	if _, err := c.ResourceAttributeMap(); err != nil {
+		v.problem("resource-attributes", "%v", err)
+	}
+
+	if _, err := c.OTLPHeaders(); err != nil {
+		v.problem("otlp-headers", "%v", err)
+	}
+	for _, file := range []struct{ name, path string }{
+		{"otlp-certificate", c.Certificate},
+		{"otlp-client-certificate", c.ClientCertificate},
+		{"otlp-client-key", c.ClientKey},
+	} {
+		if file.path == "" {
+			continue
+		}
+		if _, err := os.Stat(file.path); err != nil {
+			v.problem(file.name, "cannot be read: %v", err)
+		}
+		v.check(c.UsesTLS(), file.name, "is set but otlp-insecure (%s) is true; set it to false or use an https endpoint to connect with TLS", describe("otlp-insecure"))
+	}
+	v.check((c.ClientCertificate == "") == (c.ClientKey == ""), "otlp-client-certificate", "and otlp-client-key (%s) must be set together for mTLS", describe("otlp-client-key"))
+}
+
+// validateGitLabAPI checks the settings of the GitLab API connection
+func (c *Config) validateGitLabAPI(v *validator) {
+	v.check(c.Token != "", "gitlab-token", "is required")
+	v.check(c.TokenType == "job" || c.TokenType == "private", "gitlab-token-type", "must be job or private, got %q", c.TokenType)
+	if c.ServerURL == "" {
+		v.problem("gitlab-server-url", "is required")
+	} else {
+		u, err := url.Parse(c.ServerURL)
+		v.check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", "gitlab-server-url", "must be an http or https URL, got %q", c.ServerURL)
+	}
+	v.check(c.GitLabRetries >= 0, "gitlab-retries", "must not be negative, got %d", c.GitLabRetries)
+	v.check(c.GitLabRetryWaitMax > 0, "gitlab-retry-wait-max", "must be positive, got %s", c.GitLabRetryWaitMax)
+	v.check(c.GitLabTimeout > 0, "gitlab-timeout", "must be positive, got %s", c.GitLabTimeout)
+	v.check(!c.JobNeeds || c.TokenType == "private", "job-needs", "requires gitlab-token-type (%s) to be private, as the GraphQL API does not accept CI job tokens", describe("gitlab-token-type"))
+}
+
+// validateGitLab checks the settings of the exported pipelines
+func (c *Config) validateGitLab(v *validator) {
+	if c.ProjectID != "" {
+		v.check(isProjectID(c.ProjectID), "project-id", "must be a numeric ID or a path such as group/project, got %q", c.ProjectID)
+	}
+	if c.PipelineID != "" {
+		v.check(isPositiveInt(c.PipelineID), "pipeline-id", "must be a positive integer, got %q", c.PipelineID)
+	}
+	if c.ParentPipelineID != "" {
+		v.check(isPositiveInt(c.ParentPipelineID), "parent-pipeline-id", "must be a positive integer, got %q", c.ParentPipelineID)
+	}
+	if c.ParentProjectID != "" {
+		v.check(isPositiveInt(c.ParentProjectID), "parent-project-id", "must be a positive integer, got %q", c.ParentProjectID)
+	}
+	if c.TraceParent != "" {
+		v.check(traceParentRegex.MatchString(c.TraceParent), "traceparent", "must be a W3C traceparent such as 00-<32 hex>-<16 hex>-01, got %q", c.TraceParent)
+	}
+
+	v.check(c.JobsPerPage >= 1 && c.JobsPerPage <= 100, "gitlab-jobs-per-page", "must be between 1 and 100, got %d", c.JobsPerPage)
+	v.check(c.MaxJobs >= 0, "gitlab-max-jobs", "must not be negative, got %d", c.MaxJobs)
+	v.check(c.LogMaxBytes >= 0, "log-max-bytes", "must not be negative, got %d", c.LogMaxBytes)
+	v.check(c.LogTailLines >= 0, "log-tail-lines", "must not be negative, got %d", c.LogTailLines)
+	v.check(c.UnstartedJobs == "drop" || c.UnstartedJobs == "span" || c.UnstartedJobs == "event", "unstarted-jobs", "must be drop, span or event, got %q", c.UnstartedJobs)
+	v.check(c.ExportTimeout >= 0, "export-timeout", "must not be negative, got %s", c.ExportTimeout)
+	v.check(c.DownstreamMaxDepth >= 0, "export-downstream-max-depth", "must not be negative, got %d", c.DownstreamMaxDepth)
+	v.check(c.DryRunFormat == "text" || c.DryRunFormat == "json", "dry-run-format", "must be text or json, got %q", c.DryRunFormat)
+}
+
+// ValidateCommand checks the settings used by every command plus those
+// required by the given command, and reports all problems at once, before
+// any network call is made. Replay only talks to the collector, so
+// only the OTLP settings are checked for it, and an export from captured
+// API responses needs no GitLab connection.
+func (c *Config) ValidateCommand(command string) error {
+	v := &validator{}
+	c.validateOTLP(v)
+	if command == "replay" {
+		v.check(IsHTTPProtocol(c.Protocol) || c.Protocol == "grpc", "otlp-protocol", "must be http/protobuf or grpc to replay to a collector, got %q", c.Protocol)
+		return v.err()
+	}
+	if command != "export" || c.CaptureDir == "" {
+		c.validateGitLabAPI(v)
+	}
+	c.validateGitLab(v)
+
+	// Exporting commands other than export would record dry-run pipelines
+	// as exported or never print the tree
+	v.check(!c.DryRun || command == "export", "dry-run", "is only supported by the export command")
+	v.check(c.CaptureDir == "" || command == "export" || command == "capture", "capture-dir", "is only supported by the export and capture commands")
+
+	switch command {
+	case "export":
+		v.check(c.ProjectID != "", "project-id", "is required to export a pipeline")
+		v.check(c.PipelineID != "", "pipeline-id", "is required to export a pipeline")
+	case "capture":
+		v.check(c.ProjectID != "", "project-id", "is required to capture a pipeline")
+		v.check(c.PipelineID != "", "pipeline-id", "is required to capture a pipeline")
+		v.check(c.CaptureDir != "", "capture-dir", "is required to capture a pipeline")
+	case "serve":
+		v.check(c.WebhookSecret != "", "webhook-secret", "is required in serve mode")
+		v.check(isHostPort(c.ListenAddr), "webhook-listen-addr", "must be [host]:port, got %q", c.ListenAddr)
+		v.check(c.WebhookWorkers >= 1, "webhook-workers", "must be at least 1, got %d", c.WebhookWorkers)
+	case "poll":
+		if len(c.PollProjects) == 0 && len(c.PollGroups) == 0 {
+			v.problem("poll-projects", "or poll-groups (%s) is required in poll mode", describe("poll-groups"))
+		}
+		v.check(c.PollInterval > 0, "poll-interval", "must be positive, got %s", c.PollInterval)
+		v.check(c.PollLookback >= 0, "poll-lookback", "must not be negative, got %s", c.PollLookback)
+		v.check(c.CheckpointFile != "", "checkpoint-file", "is required in poll mode")
+	case "backfill":
+		v.check(c.CheckpointFile != "", "checkpoint-file", "is required for backfill")
+	default:
+		v.errs = append(v.errs, fmt.Errorf("unknown command %q", command))
+	}
+
+	return v.err()
+}
+
+// validator collects validation problems
+type validator struct {
+	errs []error
+}
+
+// check records a problem with the named setting unless ok
+func (v *validator) check(ok bool, name, format string, args ...interface{}) {
+	if !ok {
+		v.problem(name, format, args...)
+	}
+}
+
+// problem records a problem with the named setting, telling where it is set
+func (v *validator) problem(name, format string, args ...interface{}) {
+	v.errs = append(v.errs, fmt.Errorf("%s (%s) %s", name, describe(name), fmt.Sprintf(format, args...)))
+}
+
+func (v *validator) err() error {
+	return errors.Join(v.errs...)
+}
+
+// describe lists the flag and environment variables of the named setting
+func describe(name string) string {
+	for _, s := range settings {
+		if s.name == name {
+			return strings.Join(append([]string{"-" + s.name}, s.env...), ", ")
+		}
+	}
+	return "-" + name
+}
+
+func isHostPort(value string) bool {
+	_, port, err := net.SplitHostPort(value)
+	if err != nil {
+		return false
+	}
+	_, err = strconv.ParseUint(port, 10, 16)
+	return err == nil
+}
+
+// isEndpoint reports whether value is host:port or an http(s) URL with a host
+func isEndpoint(value string) bool {
+	if !strings.Contains(value, "://") {
+		return isHostPort(value)
+	}
+	u, err := url.Parse(value)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func isPositiveInt(value string) bool {
+	n, err := strconv.Atoi(value)
+	return err == nil && n > 0
+}
+
+func isProjectID(value string) bool {
+	return isPositiveInt(value) || projectPathRegex.MatchString(value)
+}
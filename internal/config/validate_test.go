@@ -0,0 +1,211 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func validConfig() *Config {
+	cfg := Default()
+	cfg.Token = "token"
+	cfg.ServerURL = "https://gitlab.example.com"
+	cfg.ProjectID = "group/project"
+	cfg.PipelineID = "123"
+	return cfg
+}
+
+func TestValidateAcceptsValidConfig(t *testing.T) {
+	if err := validConfig().ValidateCommand("export"); err != nil {
+		t.Errorf("expected valid config, got %v", err)
+	}
+}
+
+func TestValidateReportsAllProblems(t *testing.T) {
+	cfg := validConfig()
+	cfg.Token = ""
+	cfg.ServerURL = "gitlab.example.com"
+	cfg.PipelineID = "abc"
+	cfg.Protocol = "udp"
+	cfg.JobsPerPage = 500
+
+	err := cfg.ValidateCommand("export")
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+
+	problems := strings.Split(err.Error(), "\n")
+	if len(problems) != 5 {
+		t.Errorf("expected 5 problems, got %d: %v", len(problems), problems)
+	}
+	for _, want := range []string{"GITLAB_TOKEN", "GITLAB_SERVER_URL", "CI_PIPELINE_ID", "OTEL_EXPORTER_OTLP_PROTOCOL", "GITLAB_JOBS_PER_PAGE"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected a problem naming %s, got %v", want, err)
+		}
+	}
+}
+
+func TestValidateEndpointMatchesProtocol(t *testing.T) {
+	tests := []struct {
+		protocol string
+		endpoint string
+		valid    bool
+	}{
+		{"http", "", true},
+		{"http", "collector:4318", true},
+		{"grpc", "collector", false},
+		{"http/protobuf", "https://collector.example.com/otlp", true},
+		{"http", "ftp://collector:4318", false},
+		{"http/json", "", false},
+		{"stdout", "", true},
+		{"stdout", "collector:4318", false},
+		{"file", "", true},
+		{"file", "collector:4318", false},
+	}
+
+	for _, tt := range tests {
+		cfg := validConfig()
+		cfg.Protocol = tt.protocol
+		cfg.Endpoint = tt.endpoint
+		if err := cfg.ValidateCommand("export"); (err == nil) != tt.valid {
+			t.Errorf("Validate() with protocol=%s, endpoint=%s: valid=%v, got %v", tt.protocol, tt.endpoint, tt.valid, err)
+		}
+	}
+}
+
+func TestValidateCommandRequirements(t *testing.T) {
+	cfg := validConfig()
+	cfg.ProjectID = ""
+	cfg.PipelineID = ""
+
+	if err := cfg.ValidateCommand("export"); err == nil || !strings.Contains(err.Error(), "CI_PROJECT_ID") {
+		t.Errorf("export should require a project ID, got %v", err)
+	}
+	if err := cfg.ValidateCommand("serve"); err == nil || !strings.Contains(err.Error(), "WEBHOOK_SECRET") {
+		t.Errorf("serve should require a webhook secret, got %v", err)
+	}
+	if err := cfg.ValidateCommand("poll"); err == nil || !strings.Contains(err.Error(), "POLL_GROUPS") {
+		t.Errorf("poll should require projects or groups, got %v", err)
+	}
+	if err := cfg.ValidateCommand("backfill"); err != nil {
+		t.Errorf("backfill should not require a pipeline, got %v", err)
+	}
+}
+
+func TestValidateFileProtocol(t *testing.T) {
+	cfg := validConfig()
+	cfg.Protocol = "file"
+	cfg.OTLPFile = ""
+	cfg.ExportLogs = true
+
+	err := cfg.ValidateCommand("export")
+	if err == nil || !strings.Contains(err.Error(), "OTLP_FILE") || !strings.Contains(err.Error(), "EXPORT_LOGS") {
+		t.Errorf("file protocol should require a file and reject logs, got %v", err)
+	}
+}
+
+func TestValidateReplayChecksOTLPOnly(t *testing.T) {
+	cfg := Default()
+	cfg.Protocol = "grpc"
+	if err := cfg.ValidateCommand("replay"); err != nil {
+		t.Errorf("replay should not require GitLab settings, got %v", err)
+	}
+
+	cfg.Protocol = "file"
+	if err := cfg.ValidateCommand("replay"); err == nil || !strings.Contains(err.Error(), "OTEL_EXPORTER_OTLP_PROTOCOL") {
+		t.Errorf("replay should require a collector protocol, got %v", err)
+	}
+}
+
+func TestValidateRejectsInvalidTLSCombinations(t *testing.T) {
+	cert := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(cert, []byte("pem"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Certificates while insecure is still on
+	cfg := validConfig()
+	cfg.Certificate = cert
+	if err := cfg.ValidateCommand("export"); err == nil || !strings.Contains(err.Error(), "OTEL_EXPORTER_OTLP_INSECURE") {
+		t.Errorf("expected certificate with insecure to be rejected, got %v", err)
+	}
+
+	// Client certificate without key
+	cfg = validConfig()
+	cfg.Insecure = false
+	cfg.ClientCertificate = cert
+	if err := cfg.ValidateCommand("export"); err == nil || !strings.Contains(err.Error(), "OTEL_EXPORTER_OTLP_CLIENT_KEY") {
+		t.Errorf("expected client certificate without key to be rejected, got %v", err)
+	}
+
+	// Missing CA file
+	cfg = validConfig()
+	cfg.Insecure = false
+	cfg.Certificate = filepath.Join(t.TempDir(), "missing.pem")
+	if err := cfg.ValidateCommand("export"); err == nil || !strings.Contains(err.Error(), "cannot be read") {
+		t.Errorf("expected missing certificate file to be rejected, got %v", err)
+	}
+
+	cfg = validConfig()
+	cfg.Insecure = false
+	cfg.Certificate = cert
+	if err := cfg.ValidateCommand("export"); err != nil {
+		t.Errorf("expected TLS with a CA certificate to be valid, got %v", err)
+	}
+}
+
+func TestValidateDryRun(t *testing.T) {
+	cfg := validConfig()
+	cfg.DryRun = true
+	if err := cfg.ValidateCommand("export"); err != nil {
+		t.Errorf("export should support dry run, got %v", err)
+	}
+	if err := cfg.ValidateCommand("poll"); err == nil || !strings.Contains(err.Error(), "DRY_RUN") {
+		t.Errorf("poll should reject dry run, got %v", err)
+	}
+
+	cfg.DryRunFormat = "yaml"
+	if err := cfg.ValidateCommand("export"); err == nil || !strings.Contains(err.Error(), "DRY_RUN_FORMAT") {
+		t.Errorf("expected unknown dry-run format to be rejected, got %v", err)
+	}
+}
+
+func TestValidateCaptureDir(t *testing.T) {
+	cfg := validConfig()
+	cfg.Token = ""
+	cfg.CaptureDir = "capture"
+	if err := cfg.ValidateCommand("export"); err != nil {
+		t.Errorf("an export from captured responses should not need a token, got %v", err)
+	}
+	if err := cfg.ValidateCommand("capture"); err == nil || !strings.Contains(err.Error(), "GITLAB_TOKEN") {
+		t.Errorf("capture should require a token, got %v", err)
+	}
+	if err := cfg.ValidateCommand("backfill"); err == nil || !strings.Contains(err.Error(), "CAPTURE_DIR") {
+		t.Errorf("backfill should reject a capture directory, got %v", err)
+	}
+}
+
+func TestValidateJobNeedsToken(t *testing.T) {
+	cfg := validConfig()
+	cfg.JobNeeds = true
+	if err := cfg.ValidateCommand("export"); err == nil || !strings.Contains(err.Error(), "JOB_NEEDS") {
+		t.Errorf("expected job needs with a job token to be rejected, got %v", err)
+	}
+	cfg.TokenType = "private"
+	if err := cfg.ValidateCommand("export"); err != nil {
+		t.Errorf("expected job needs with a private token to be valid, got %v", err)
+	}
+}
+
+func TestValidateLogMaxBytes(t *testing.T) {
+	cfg := validConfig()
+	cfg.LogMaxBytes = 0
+	if err := cfg.ValidateCommand("export"); err != nil {
+		t.Errorf("expected log-max-bytes 0 to disable the cap, got %v", err)
+	}
+	cfg.LogMaxBytes = -1
+	if err := cfg.ValidateCommand("export"); err == nil || !strings.Contains(err.Error(), "LOG_MAX_BYTES") {
+		t.Errorf("expected a negative log-max-bytes to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,218 @@
+package gitlab
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+)
+
+// Captured API responses are stored in one directory, named after the
+// pipeline or job they belong to. Pipeline and job IDs are unique within a
+// GitLab instance, so the project is not part of the name.
+
+func pipelineFile(dir string, pipelineID int) string {
+	return filepath.Join(dir, fmt.Sprintf("pipeline-%d.json", pipelineID))
+}
+
+func jobsFile(dir string, pipelineID int) string {
+	return filepath.Join(dir, fmt.Sprintf("pipeline-%d-jobs.json", pipelineID))
+}
+
+func bridgesFile(dir string, pipelineID int) string {
+	return filepath.Join(dir, fmt.Sprintf("pipeline-%d-bridges.json", pipelineID))
+}
+
+func variablesFile(dir string, pipelineID int) string {
+	return filepath.Join(dir, fmt.Sprintf("pipeline-%d-variables.json", pipelineID))
+}
+
+func needsFile(dir string, pipelineID int) string {
+	return filepath.Join(dir, fmt.Sprintf("pipeline-%d-needs.json", pipelineID))
+}
+
+func traceFile(dir string, jobID int) string {
+	return filepath.Join(dir, fmt.Sprintf("job-%d.log", jobID))
+}
+
+// Recorder is a PipelineSource that saves every response of another source
+// to a directory, to be read back with FileSource
+type Recorder struct {
+	source PipelineSource
+	dir    string
+}
+
+// NewRecorder creates a recorder saving the responses of source to dir
+func NewRecorder(source PipelineSource, dir string) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create capture directory: %w", err)
+	}
+	return &Recorder{source: source, dir: dir}, nil
+}
+
+// FetchPipelineByID fetches and saves a pipeline
+func (r *Recorder) FetchPipelineByID(ctx context.Context, projectID string, pipelineID int) (*PipelineData, error) {
+	pipeline, err := r.source.FetchPipelineByID(ctx, projectID, pipelineID)
+	if err != nil {
+		return nil, err
+	}
+	return pipeline, writeJSON(pipelineFile(r.dir, pipelineID), pipeline.Pipeline)
+}
+
+// FetchJobsByID fetches and saves the jobs of a pipeline
+func (r *Recorder) FetchJobsByID(ctx context.Context, projectID string, pipelineID int) ([]*JobData, error) {
+	jobs, err := r.source.FetchJobsByID(ctx, projectID, pipelineID)
+	if err != nil {
+		return nil, err
+	}
+	raw := make([]*gitlab.Job, 0, len(jobs))
+	for _, job := range jobs {
+		raw = append(raw, job.Job)
+	}
+	return jobs, writeJSON(jobsFile(r.dir, pipelineID), raw)
+}
+
+// FetchBridgesByID fetches and saves the bridges of a pipeline
+func (r *Recorder) FetchBridgesByID(ctx context.Context, projectID string, pipelineID int) ([]*BridgeData, error) {
+	bridges, err := r.source.FetchBridgesByID(ctx, projectID, pipelineID)
+	if err != nil {
+		return nil, err
+	}
+	raw := make([]*gitlab.Bridge, 0, len(bridges))
+	for _, bridge := range bridges {
+		raw = append(raw, bridge.Bridge)
+	}
+	return bridges, writeJSON(bridgesFile(r.dir, pipelineID), raw)
+}
+
+// FetchPipelineVariables fetches and saves the variables of a pipeline
+func (r *Recorder) FetchPipelineVariables(ctx context.Context, projectID string, pipelineID int) ([]*gitlab.PipelineVariable, error) {
+	variables, err := r.source.FetchPipelineVariables(ctx, projectID, pipelineID)
+	if err != nil {
+		return nil, err
+	}
+	return variables, writeJSON(variablesFile(r.dir, pipelineID), variables)
+}
+
+// FetchJobTrace fetches and saves the log of a job
+func (r *Recorder) FetchJobTrace(ctx context.Context, projectID string, jobID int) (io.Reader, error) {
+	trace, err := r.source.FetchJobTrace(ctx, projectID, jobID)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(trace)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(traceFile(r.dir, jobID), data, 0o644); err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
+
+// FetchJobNeeds fetches and saves the needs of the jobs of a pipeline
+func (r *Recorder) FetchJobNeeds(ctx context.Context, pipeline *PipelineData) (JobNeeds, error) {
+	needs, err := r.source.FetchJobNeeds(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	return needs, writeJSON(needsFile(r.dir, pipeline.ID), needs)
+}
+
+func writeJSON(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// FileSource is a PipelineSource reading the API responses saved by a
+// Recorder, or downloaded from the GitLab API by hand, from a directory
+type FileSource struct {
+	dir string
+}
+
+// NewFileSource creates a source reading the responses saved in dir
+func NewFileSource(dir string) *FileSource {
+	return &FileSource{dir: dir}
+}
+
+// FetchPipelineByID reads a saved pipeline
+func (s *FileSource) FetchPipelineByID(ctx context.Context, projectID string, pipelineID int) (*PipelineData, error) {
+	var pipeline *gitlab.Pipeline
+	if err := readJSON(pipelineFile(s.dir, pipelineID), &pipeline); err != nil {
+		return nil, err
+	}
+	return newPipelineData(pipeline)
+}
+
+// FetchJobsByID reads the saved jobs of a pipeline
+func (s *FileSource) FetchJobsByID(ctx context.Context, projectID string, pipelineID int) ([]*JobData, error) {
+	var jobs []*gitlab.Job
+	if err := readJSON(jobsFile(s.dir, pipelineID), &jobs); err != nil {
+		return nil, err
+	}
+	return newJobData(jobs), nil
+}
+
+// FetchBridgesByID reads the saved bridges of a pipeline
+func (s *FileSource) FetchBridgesByID(ctx context.Context, projectID string, pipelineID int) ([]*BridgeData, error) {
+	var bridges []*gitlab.Bridge
+	if err := readJSON(bridgesFile(s.dir, pipelineID), &bridges); err != nil {
+		return nil, err
+	}
+	return newBridgeData(bridges), nil
+}
+
+// FetchPipelineVariables reads the saved variables of a pipeline
+func (s *FileSource) FetchPipelineVariables(ctx context.Context, projectID string, pipelineID int) ([]*gitlab.PipelineVariable, error) {
+	var variables []*gitlab.PipelineVariable
+	if err := readJSON(variablesFile(s.dir, pipelineID), &variables); err != nil {
+		return nil, err
+	}
+	return variables, nil
+}
+
+// FetchJobTrace reads the saved log of a job
+func (s *FileSource) FetchJobTrace(ctx context.Context, projectID string, jobID int) (io.Reader, error) {
+	data, err := os.ReadFile(traceFile(s.dir, jobID))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("log of job %d was not captured: %w", jobID, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
+
+// FetchJobNeeds reads the saved needs of the jobs of a pipeline
+func (s *FileSource) FetchJobNeeds(ctx context.Context, pipeline *PipelineData) (JobNeeds, error) {
+	var needs JobNeeds
+	err := readJSON(needsFile(s.dir, pipeline.ID), &needs)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("job needs of pipeline %d were not captured, which takes a private token: %w", pipeline.ID, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return needs, nil
+}
+
+func readJSON(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("invalid JSON in %s: %w", path, err)
+	}
+	return nil
+}
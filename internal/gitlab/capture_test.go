@@ -0,0 +1,113 @@
+package gitlab
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+)
+
+// staticSource serves fixed data for any pipeline
+type staticSource struct{}
+
+func (staticSource) FetchPipelineByID(ctx context.Context, projectID string, pipelineID int) (*PipelineData, error) {
+	return newPipelineData(&gitlab.Pipeline{ID: pipelineID, ProjectID: 1, Status: "success"})
+}
+
+func (staticSource) FetchJobsByID(ctx context.Context, projectID string, pipelineID int) ([]*JobData, error) {
+	return newJobData([]*gitlab.Job{{ID: 11, Name: "build", Stage: "build"}}), nil
+}
+
+func (staticSource) FetchBridgesByID(ctx context.Context, projectID string, pipelineID int) ([]*BridgeData, error) {
+	return newBridgeData([]*gitlab.Bridge{{ID: 12, Name: "deploy", DownstreamPipeline: &gitlab.PipelineInfo{ID: 30}}}), nil
+}
+
+func (staticSource) FetchPipelineVariables(ctx context.Context, projectID string, pipelineID int) ([]*gitlab.PipelineVariable, error) {
+	return []*gitlab.PipelineVariable{{Key: "TRACEPARENT", Value: "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"}}, nil
+}
+
+func (staticSource) FetchJobTrace(ctx context.Context, projectID string, jobID int) (io.Reader, error) {
+	return strings.NewReader("Running with gitlab-runner\n"), nil
+}
+
+func (staticSource) FetchJobNeeds(ctx context.Context, pipeline *PipelineData) (JobNeeds, error) {
+	return JobNeeds{"build": {}, "test": {"build"}}, nil
+}
+
+func TestRecorderRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	recorder, err := NewRecorder(staticSource{}, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := recorder.FetchPipelineByID(context.Background(), "group/app", 20); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := recorder.FetchJobsByID(context.Background(), "group/app", 20); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := recorder.FetchBridgesByID(context.Background(), "group/app", 20); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := recorder.FetchPipelineVariables(context.Background(), "group/app", 20); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := recorder.FetchJobTrace(context.Background(), "group/app", 11); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := recorder.FetchJobNeeds(context.Background(), &PipelineData{Pipeline: &gitlab.Pipeline{ID: 20}}); err != nil {
+		t.Fatal(err)
+	}
+
+	source := NewFileSource(dir)
+	pipeline, err := source.FetchPipelineByID(context.Background(), "1", 20)
+	if err != nil {
+		t.Fatalf("saved pipeline not readable: %v", err)
+	}
+	if pipeline.ID != 20 || pipeline.Status != "success" || pipeline.Raw["status"] != "success" {
+		t.Errorf("unexpected pipeline: %+v", pipeline.Pipeline)
+	}
+
+	jobs, err := source.FetchJobsByID(context.Background(), "1", 20)
+	if err != nil || len(jobs) != 1 || jobs[0].Name != "build" {
+		t.Errorf("unexpected jobs: %v, %v", jobs, err)
+	}
+	bridges, err := source.FetchBridgesByID(context.Background(), "1", 20)
+	if err != nil || len(bridges) != 1 || bridges[0].DownstreamPipeline.ID != 30 {
+		t.Errorf("unexpected bridges: %v, %v", bridges, err)
+	}
+	variables, err := source.FetchPipelineVariables(context.Background(), "1", 20)
+	if err != nil || len(variables) != 1 || variables[0].Key != "TRACEPARENT" {
+		t.Errorf("unexpected variables: %v, %v", variables, err)
+	}
+	trace, err := source.FetchJobTrace(context.Background(), "1", 11)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := io.ReadAll(trace); string(data) != "Running with gitlab-runner\n" {
+		t.Errorf("unexpected job log %q", data)
+	}
+	needs, err := source.FetchJobNeeds(context.Background(), pipeline)
+	if err != nil || len(needs["test"]) != 1 || needs["test"][0] != "build" {
+		t.Errorf("unexpected needs: %v, %v", needs, err)
+	}
+}
+
+func TestFileSourceMissingPipeline(t *testing.T) {
+	if _, err := NewFileSource(t.TempDir()).FetchPipelineByID(context.Background(), "1", 99); err == nil {
+		t.Error("expected an error for a pipeline that was not captured")
+	}
+}
+
+func TestFileSourceNotCaptured(t *testing.T) {
+	source := NewFileSource(t.TempDir())
+	if _, err := source.FetchJobTrace(context.Background(), "1", 7); err == nil || !strings.Contains(err.Error(), "was not captured") {
+		t.Errorf("expected a job log that was not captured to be reported, got %v", err)
+	}
+	pipeline := &PipelineData{Pipeline: &gitlab.Pipeline{ID: 99}}
+	if _, err := source.FetchJobNeeds(context.Background(), pipeline); err == nil || !strings.Contains(err.Error(), "were not captured") {
+		t.Errorf("expected job needs that were not captured to be reported, got %v", err)
+	}
+}
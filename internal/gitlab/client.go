@@ -1,12 +1,16 @@
 package gitlab
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"log"
+	"net/http"
 	"strconv"
+	"time"
 
 	gitlab "gitlab.com/gitlab-org/api/client-go"
 	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/config"
-	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/utils"
 )
 
 // Client wraps GitLab API client with configuration
@@ -15,9 +19,28 @@ type Client struct {
 	config *config.Config
 }
 
-// NewClient creates a new GitLab client
+// NewClient creates a new GitLab client. The token is sent as a CI job token
+// unless the configuration says it is a personal, project or group access token.
+// Failed requests are retried as the configuration says, and each attempt
+// times out after the configured GitLab timeout.
 func NewClient(cfg *config.Config) (*Client, error) {
-	client, err := gitlab.NewJobClient(cfg.Token, gitlab.WithBaseURL(cfg.ServerURL))
+	newClient := gitlab.NewJobClient
+	if cfg.TokenType == "private" {
+		newClient = gitlab.NewClient
+	}
+
+	policy := retryPolicy{retries: cfg.GitLabRetries, waitMax: cfg.GitLabRetryWaitMax}
+	client, err := newClient(cfg.Token,
+		gitlab.WithBaseURL(cfg.ServerURL),
+		gitlab.WithHTTPClient(&http.Client{
+			Transport: http.DefaultTransport.(*http.Transport).Clone(),
+			Timeout:   cfg.GitLabTimeout,
+		}),
+		gitlab.WithCustomRetry(policy.checkRetry),
+		gitlab.WithCustomBackoff(policy.backoff),
+		gitlab.WithCustomRetryMax(cfg.GitLabRetries),
+		gitlab.WithCustomRetryWaitMinMax(min(retryWaitMin, cfg.GitLabRetryWaitMax), cfg.GitLabRetryWaitMax),
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -29,47 +52,256 @@ func NewClient(cfg *config.Config) (*Client, error) {
 }
 
 // FetchPipeline retrieves pipeline data from GitLab API
-func (c *Client) FetchPipeline() (*PipelineData, error) {
-	pipelineID, _ := strconv.Atoi(c.config.PipelineID)
+func (c *Client) FetchPipeline(ctx context.Context) (*PipelineData, error) {
+	pipelineID, err := c.pipelineID()
+	if err != nil {
+		return nil, err
+	}
+	return c.FetchPipelineByID(ctx, c.config.ProjectID, pipelineID)
+}
 
-	pipeline, _, err := c.client.Pipelines.GetPipeline(c.config.ProjectID, pipelineID, nil)
+// FetchPipelineByID retrieves data for any pipeline of any project
+func (c *Client) FetchPipelineByID(ctx context.Context, projectID string, pipelineID int) (*PipelineData, error) {
+	pipeline, _, err := c.client.Pipelines.GetPipeline(projectID, pipelineID, gitlab.WithContext(ctx))
 	if err != nil {
 		return nil, err
 	}
+	return newPipelineData(pipeline)
+}
 
-	raw, err := utils.StructToMap(pipeline)
+// FetchJobs retrieves all jobs for the pipeline, walking every result page
+func (c *Client) FetchJobs(ctx context.Context) ([]*JobData, error) {
+	pipelineID, err := c.pipelineID()
 	if err != nil {
 		return nil, err
 	}
-	utils.CleanRaw(raw)
+	return c.FetchJobsByID(ctx, c.config.ProjectID, pipelineID)
+}
+
+// FetchJobsByID retrieves all jobs for any pipeline of any project
+func (c *Client) FetchJobsByID(ctx context.Context, projectID string, pipelineID int) ([]*JobData, error) {
+	opts := &gitlab.ListJobsOptions{
+		ListOptions: gitlab.ListOptions{
+			PerPage: c.config.JobsPerPage,
+			Page:    1,
+		},
+		IncludeRetried: gitlab.Ptr(c.config.RetriedJobs),
+	}
 
-	return &PipelineData{Pipeline: pipeline, Raw: raw}, nil
+	var jobs []*gitlab.Job
+	total := 0
+	for {
+		page, resp, err := c.client.Jobs.ListPipelineJobs(projectID, pipelineID, opts, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, page...)
+		if resp.TotalItems > 0 {
+			total = resp.TotalItems
+		}
+
+		if c.config.MaxJobs > 0 && len(jobs) >= c.config.MaxJobs {
+			jobs = jobs[:c.config.MaxJobs]
+			break
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	reportCount("job", len(jobs), total, c.config.MaxJobs)
+
+	return newJobData(jobs), nil
 }
 
-// FetchJobs retrieves all jobs for the pipeline
-func (c *Client) FetchJobs() ([]*JobData, error) {
-	pipelineID, _ := strconv.Atoi(c.config.PipelineID)
+// FetchBridges retrieves all bridge (trigger) jobs for the pipeline
+func (c *Client) FetchBridges(ctx context.Context) ([]*BridgeData, error) {
+	pipelineID, err := c.pipelineID()
+	if err != nil {
+		return nil, err
+	}
+	return c.FetchBridgesByID(ctx, c.config.ProjectID, pipelineID)
+}
+
+// FetchBridgesByID retrieves all bridge (trigger) jobs for any pipeline of any project
+func (c *Client) FetchBridgesByID(ctx context.Context, projectID string, pipelineID int) ([]*BridgeData, error) {
+	opts := &gitlab.ListJobsOptions{
+		ListOptions: gitlab.ListOptions{
+			PerPage: c.config.JobsPerPage,
+			Page:    1,
+		},
+	}
+
+	var bridges []*gitlab.Bridge
+	total := 0
+	for {
+		page, resp, err := c.client.Jobs.ListPipelineBridges(projectID, pipelineID, opts, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, err
+		}
+		bridges = append(bridges, page...)
+		if resp.TotalItems > 0 {
+			total = resp.TotalItems
+		}
+
+		if c.config.MaxJobs > 0 && len(bridges) >= c.config.MaxJobs {
+			bridges = bridges[:c.config.MaxJobs]
+			break
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	reportCount("bridge", len(bridges), total, c.config.MaxJobs)
+
+	return newBridgeData(bridges), nil
+}
+
+// FetchJobTrace retrieves the log (trace) of a job
+func (c *Client) FetchJobTrace(ctx context.Context, projectID string, jobID int) (io.Reader, error) {
+	trace, _, err := c.client.Jobs.GetTraceFile(projectID, jobID, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return trace, nil
+}
 
-	jobs, _, err := c.client.Jobs.ListPipelineJobs(c.config.ProjectID, pipelineID, &gitlab.ListJobsOptions{}, nil)
+// FetchPipelineVariables retrieves the variables a pipeline was created with
+func (c *Client) FetchPipelineVariables(ctx context.Context, projectID string, pipelineID int) ([]*gitlab.PipelineVariable, error) {
+	variables, _, err := c.client.Pipelines.GetPipelineVariables(projectID, pipelineID, gitlab.WithContext(ctx))
 	if err != nil {
 		return nil, err
 	}
+	return variables, nil
+}
+
+// PipelineFilter selects the pipelines returned by ListPipelines
+type PipelineFilter struct {
+	UpdatedAfter  *time.Time
+	UpdatedBefore *time.Time
+	Ref           string
+	Status        string
+}
+
+// ListPipelines retrieves the pipelines of a project matching filter, oldest
+// update first
+func (c *Client) ListPipelines(ctx context.Context, projectID string, filter PipelineFilter) ([]*gitlab.PipelineInfo, error) {
+	opts := &gitlab.ListProjectPipelinesOptions{
+		ListOptions: gitlab.ListOptions{
+			PerPage: 100,
+			Page:    1,
+		},
+		UpdatedAfter:  filter.UpdatedAfter,
+		UpdatedBefore: filter.UpdatedBefore,
+		OrderBy:       gitlab.Ptr("updated_at"),
+		Sort:          gitlab.Ptr("asc"),
+	}
+	if filter.Ref != "" {
+		opts.Ref = gitlab.Ptr(filter.Ref)
+	}
+	if filter.Status != "" {
+		opts.Status = gitlab.Ptr(gitlab.BuildStateValue(filter.Status))
+	}
 
-	var jobData []*JobData
-	for _, job := range jobs {
-		raw, err := utils.StructToMap(job)
+	var pipelines []*gitlab.PipelineInfo
+	for {
+		page, resp, err := c.client.Pipelines.ListProjectPipelines(projectID, opts, gitlab.WithContext(ctx))
 		if err != nil {
-			log.Printf("failed to convert job %d to map: %v", job.ID, err)
-			continue
+			return nil, err
+		}
+		pipelines = append(pipelines, page...)
+
+		if resp.NextPage == 0 {
+			break
 		}
-		utils.CleanRaw(raw)
-		jobData = append(jobData, &JobData{Job: job, Raw: raw})
+		opts.Page = resp.NextPage
 	}
 
-	return jobData, nil
+	return pipelines, nil
 }
 
-// GetClient returns the underlying GitLab client
-func (c *Client) GetClient() *gitlab.Client {
-	return c.client
+// ListGroupProjects retrieves the IDs of all non-archived projects of a
+// group, including its subgroups
+func (c *Client) ListGroupProjects(ctx context.Context, groupID string) ([]string, error) {
+	opts := &gitlab.ListGroupProjectsOptions{
+		ListOptions: gitlab.ListOptions{
+			PerPage: 100,
+			Page:    1,
+		},
+		Archived:         gitlab.Ptr(false),
+		IncludeSubGroups: gitlab.Ptr(true),
+		Simple:           gitlab.Ptr(true),
+	}
+
+	var projectIDs []string
+	for {
+		page, resp, err := c.client.Groups.ListGroupProjects(groupID, opts, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, err
+		}
+		for _, project := range page {
+			projectIDs = append(projectIDs, strconv.Itoa(project.ID))
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return projectIDs, nil
+}
+
+// ResolveProjects returns the given projects plus the projects of the given
+// groups, without duplicates
+func (c *Client) ResolveProjects(ctx context.Context, projects, groups []string) ([]string, error) {
+	seen := map[string]bool{}
+	var resolved []string
+	add := func(project string) {
+		if !seen[project] {
+			seen[project] = true
+			resolved = append(resolved, project)
+		}
+	}
+
+	for _, project := range projects {
+		add(project)
+	}
+	for _, group := range groups {
+		groupProjects, err := c.ListGroupProjects(ctx, group)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list projects of group %s: %w", group, err)
+		}
+		for _, project := range groupProjects {
+			add(project)
+		}
+	}
+
+	return resolved, nil
+}
+
+// pipelineID parses the configured pipeline ID
+func (c *Client) pipelineID() (int, error) {
+	pipelineID, err := strconv.Atoi(c.config.PipelineID)
+	if err != nil {
+		return 0, fmt.Errorf("invalid pipeline ID %q: %w", c.config.PipelineID, err)
+	}
+	return pipelineID, nil
+}
+
+// reportCount prints how many jobs or bridges (kind) were fetched compared
+// with the total reported by GitLab. The total is unknown (0) when GitLab
+// omits X-Total.
+func reportCount(kind string, fetched, total, limit int) {
+	if total == 0 {
+		fmt.Printf("Fetched %d %ss (total not reported by GitLab)\n", fetched, kind)
+		return
+	}
+	fmt.Printf("Fetched %d of %d %ss\n", fetched, total, kind)
+	if fetched < total {
+		log.Printf("%s list truncated: fetched %d of %d %ss (GITLAB_MAX_JOBS=%d)", kind, fetched, total, kind, limit)
+	}
 }
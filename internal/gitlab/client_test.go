@@ -0,0 +1,133 @@
+package gitlab
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/config"
+)
+
+func newPagedJobsServer(t *testing.T, totalJobs int) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page == 0 {
+			page = 1
+		}
+
+		var jobs []map[string]interface{}
+		for id := (page-1)*perPage + 1; id <= page*perPage && id <= totalJobs; id++ {
+			jobs = append(jobs, map[string]interface{}{"id": id, "name": fmt.Sprintf("job-%d", id)})
+		}
+
+		totalPages := (totalJobs + perPage - 1) / perPage
+		w.Header().Set("X-Total", strconv.Itoa(totalJobs))
+		w.Header().Set("X-Total-Pages", strconv.Itoa(totalPages))
+		w.Header().Set("X-Page", strconv.Itoa(page))
+		if page < totalPages {
+			w.Header().Set("X-Next-Page", strconv.Itoa(page+1))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(jobs)
+	}))
+}
+
+func TestFetchJobsPaginates(t *testing.T) {
+	server := newPagedJobsServer(t, 150)
+	defer server.Close()
+
+	client, err := NewClient(&config.Config{
+		ServerURL:   server.URL,
+		ProjectID:   "1",
+		PipelineID:  "2",
+		JobsPerPage: 20,
+		MaxJobs:     1000,
+	})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	jobs, err := client.FetchJobs(context.Background())
+	if err != nil {
+		t.Fatalf("FetchJobs failed: %v", err)
+	}
+	if len(jobs) != 150 {
+		t.Errorf("expected 150 jobs, got %d", len(jobs))
+	}
+}
+
+func TestFetchJobsRespectsMaxJobs(t *testing.T) {
+	server := newPagedJobsServer(t, 150)
+	defer server.Close()
+
+	client, err := NewClient(&config.Config{
+		ServerURL:   server.URL,
+		ProjectID:   "1",
+		PipelineID:  "2",
+		JobsPerPage: 20,
+		MaxJobs:     50,
+	})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	jobs, err := client.FetchJobs(context.Background())
+	if err != nil {
+		t.Fatalf("FetchJobs failed: %v", err)
+	}
+	if len(jobs) != 50 {
+		t.Errorf("expected 50 jobs, got %d", len(jobs))
+	}
+}
+
+func TestListPipelinesAppliesFilter(t *testing.T) {
+	var query url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[{"id": 1, "status": "success"}]`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(&config.Config{ServerURL: server.URL})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	after := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	before := after.Add(24 * time.Hour)
+	pipelines, err := client.ListPipelines(context.Background(), "1", PipelineFilter{
+		UpdatedAfter:  &after,
+		UpdatedBefore: &before,
+		Ref:           "main",
+		Status:        "success",
+	})
+	if err != nil {
+		t.Fatalf("ListPipelines failed: %v", err)
+	}
+	if len(pipelines) != 1 {
+		t.Errorf("expected 1 pipeline, got %d", len(pipelines))
+	}
+
+	expected := map[string]string{
+		"updated_after":  "2026-01-01T00:00:00Z",
+		"updated_before": "2026-01-02T00:00:00Z",
+		"ref":            "main",
+		"status":         "success",
+		"order_by":       "updated_at",
+		"sort":           "asc",
+	}
+	for key, value := range expected {
+		if got := query.Get(key); got != value {
+			t.Errorf("expected %s=%s, got %q", key, value, got)
+		}
+	}
+}
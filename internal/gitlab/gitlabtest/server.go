@@ -0,0 +1,374 @@
+// Package gitlabtest provides an in-memory fake of the GitLab API for tests.
+// It serves the pipelines, jobs, bridges, variables, job logs and job needs
+// added to it over HTTP, so the real GitLab client can be pointed at it.
+package gitlabtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/config"
+	gitlabpkg "gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/gitlab"
+)
+
+// Server is a fake GitLab API. Pipelines and jobs are looked up by ID alone,
+// as IDs are unique within a GitLab instance, so any project ID or path in
+// a request matches. Pipelines are listed by numeric project ID.
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	pipelines map[int]*gitlab.Pipeline
+	jobs      map[int][]*gitlab.Job
+	bridges   map[int][]*gitlab.Bridge
+	variables map[int][]*gitlab.PipelineVariable
+	traces    map[int]string
+	needs     map[int]map[string][]string
+	groups    map[string][]int
+	requests  []string
+}
+
+// NewServer starts a fake GitLab API, closed when the test ends
+func NewServer(t testing.TB) *Server {
+	s := &Server{
+		pipelines: map[int]*gitlab.Pipeline{},
+		jobs:      map[int][]*gitlab.Job{},
+		bridges:   map[int][]*gitlab.Bridge{},
+		variables: map[int][]*gitlab.PipelineVariable{},
+		traces:    map[int]string{},
+		needs:     map[int]map[string][]string{},
+		groups:    map[string][]int{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v4/projects/{project}/pipelines", s.listPipelines)
+	mux.HandleFunc("GET /api/v4/projects/{project}/pipelines/{pipeline}", s.getPipeline)
+	mux.HandleFunc("GET /api/v4/projects/{project}/pipelines/{pipeline}/jobs", s.listJobs)
+	mux.HandleFunc("GET /api/v4/projects/{project}/pipelines/{pipeline}/bridges", s.listBridges)
+	mux.HandleFunc("GET /api/v4/projects/{project}/pipelines/{pipeline}/variables", s.listVariables)
+	mux.HandleFunc("GET /api/v4/projects/{project}/jobs/{job}/trace", s.getTrace)
+	mux.HandleFunc("GET /api/v4/groups/{group}/projects", s.listGroupProjects)
+	mux.HandleFunc("POST /api/graphql", s.graphQL)
+
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+		s.mu.Unlock()
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Config returns a configuration reaching the fake with a private token
+func (s *Server) Config() *config.Config {
+	cfg := config.Default()
+	cfg.ServerURL = s.URL
+	cfg.Token = "test-token"
+	cfg.TokenType = "private"
+	return cfg
+}
+
+// AddPipeline adds a pipeline
+func (s *Server) AddPipeline(pipeline *gitlab.Pipeline) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pipelines[pipeline.ID] = pipeline
+}
+
+// AddJobs adds jobs to a pipeline
+func (s *Server) AddJobs(pipelineID int, jobs ...*gitlab.Job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[pipelineID] = append(s.jobs[pipelineID], jobs...)
+}
+
+// AddBridges adds bridges (trigger jobs) to a pipeline
+func (s *Server) AddBridges(pipelineID int, bridges ...*gitlab.Bridge) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bridges[pipelineID] = append(s.bridges[pipelineID], bridges...)
+}
+
+// SetVariable sets a variable of a pipeline
+func (s *Server) SetVariable(pipelineID int, key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.variables[pipelineID] = append(s.variables[pipelineID], &gitlab.PipelineVariable{Key: key, Value: value, VariableType: gitlab.EnvVariableType})
+}
+
+// SetJobTrace sets the log of a job
+func (s *Server) SetJobTrace(jobID int, log string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.traces[jobID] = log
+}
+
+// SetNeeds sets the jobs a job of a pipeline needs, served by the GraphQL
+// API
+func (s *Server) SetNeeds(pipelineID int, job string, needs ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.needs[pipelineID] == nil {
+		s.needs[pipelineID] = map[string][]string{}
+	}
+	s.needs[pipelineID][job] = needs
+}
+
+// AddGroup adds a group with the given project IDs
+func (s *Server) AddGroup(group string, projectIDs ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.groups[group] = append(s.groups[group], projectIDs...)
+}
+
+// Requests returns the method and path of every request received so far
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+func (s *Server) getPipeline(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pipeline, ok := s.pipelines[pathID(r, "pipeline")]
+	if !ok {
+		notFound(w)
+		return
+	}
+	writeJSON(w, pipeline)
+}
+
+// listPipelines serves the pipelines of a project given by its numeric ID,
+// applying the updated_after, updated_before, ref and status filters,
+// oldest update first
+func (s *Server) listPipelines(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	after, _ := time.Parse(time.RFC3339, query.Get("updated_after"))
+	before, _ := time.Parse(time.RFC3339, query.Get("updated_before"))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var pipelines []*gitlab.PipelineInfo
+	for _, p := range s.pipelines {
+		switch {
+		case strconv.Itoa(p.ProjectID) != r.PathValue("project"):
+			continue
+		case p.UpdatedAt != nil && !after.IsZero() && p.UpdatedAt.Before(after):
+			continue
+		case p.UpdatedAt != nil && !before.IsZero() && !p.UpdatedAt.Before(before):
+			continue
+		case query.Get("ref") != "" && p.Ref != query.Get("ref"):
+			continue
+		case query.Get("status") != "" && p.Status != query.Get("status"):
+			continue
+		}
+		pipelines = append(pipelines, &gitlab.PipelineInfo{
+			ID:        p.ID,
+			IID:       p.IID,
+			ProjectID: p.ProjectID,
+			Status:    p.Status,
+			Source:    p.Source,
+			Ref:       p.Ref,
+			SHA:       p.SHA,
+			Name:      p.Name,
+			WebURL:    p.WebURL,
+			UpdatedAt: p.UpdatedAt,
+			CreatedAt: p.CreatedAt,
+		})
+	}
+	sort.Slice(pipelines, func(i, j int) bool {
+		if pipelines[i].UpdatedAt == nil || pipelines[j].UpdatedAt == nil {
+			return pipelines[i].ID < pipelines[j].ID
+		}
+		return pipelines[i].UpdatedAt.Before(*pipelines[j].UpdatedAt)
+	})
+	writePage(w, r, pipelines)
+}
+
+// listJobs serves the jobs of a pipeline. Like GitLab, it leaves out
+// earlier attempts of retried jobs unless include_retried is set.
+func (s *Server) listJobs(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	jobs := s.jobs[pathID(r, "pipeline")]
+	if r.URL.Query().Get("include_retried") != "true" {
+		latest := map[string]*gitlab.Job{}
+		for _, job := range jobs {
+			if last, ok := latest[job.Name]; !ok || job.ID > last.ID {
+				latest[job.Name] = job
+			}
+		}
+		var filtered []*gitlab.Job
+		for _, job := range jobs {
+			if latest[job.Name] == job {
+				filtered = append(filtered, job)
+			}
+		}
+		jobs = filtered
+	}
+	writePage(w, r, jobs)
+}
+
+func (s *Server) listBridges(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writePage(w, r, s.bridges[pathID(r, "pipeline")])
+}
+
+func (s *Server) listVariables(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	variables := s.variables[pathID(r, "pipeline")]
+	if variables == nil {
+		variables = []*gitlab.PipelineVariable{}
+	}
+	writeJSON(w, variables)
+}
+
+func (s *Server) getTrace(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	trace, ok := s.traces[pathID(r, "job")]
+	if !ok {
+		notFound(w)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	_, _ = w.Write([]byte(trace))
+}
+
+func (s *Server) listGroupProjects(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	projectIDs, ok := s.groups[r.PathValue("group")]
+	if !ok {
+		notFound(w)
+		return
+	}
+	var projects []*gitlab.Project
+	for _, id := range projectIDs {
+		projects = append(projects, &gitlab.Project{ID: id})
+	}
+	writePage(w, r, projects)
+}
+
+// graphQLPageSize is the number of jobs in a page of the GraphQL API
+const graphQLPageSize = 100
+
+// graphQL answers the query for the needs of the jobs of a pipeline, the only
+// GraphQL query the client sends. The pipeline is looked up by its IID and
+// the project path of its web URL; cursors are offsets into its jobs.
+func (s *Server) graphQL(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Variables struct {
+			Project string `json:"project"`
+			IID     string `json:"iid"`
+			After   string `json:"after"`
+		} `json:"variables"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var pipeline *gitlab.Pipeline
+	for _, p := range s.pipelines {
+		data := &gitlabpkg.PipelineData{Pipeline: p}
+		if strconv.Itoa(p.IID) == req.Variables.IID && data.ProjectPath() == req.Variables.Project {
+			pipeline = p
+		}
+	}
+	if pipeline == nil {
+		writeJSON(w, map[string]interface{}{"data": map[string]interface{}{"project": nil}})
+		return
+	}
+
+	type node struct {
+		Name  string `json:"name"`
+		Needs struct {
+			Nodes []map[string]string `json:"nodes"`
+		} `json:"needs"`
+	}
+	jobs := s.jobs[pipeline.ID]
+	start, _ := strconv.Atoi(req.Variables.After)
+	start = min(start, len(jobs))
+	end := min(start+graphQLPageSize, len(jobs))
+	nodes := []node{}
+	for _, job := range jobs[start:end] {
+		n := node{Name: job.Name}
+		n.Needs.Nodes = []map[string]string{}
+		for _, need := range s.needs[pipeline.ID][job.Name] {
+			n.Needs.Nodes = append(n.Needs.Nodes, map[string]string{"name": need})
+		}
+		nodes = append(nodes, n)
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"data": map[string]interface{}{
+			"project": map[string]interface{}{
+				"pipeline": map[string]interface{}{
+					"jobs": map[string]interface{}{
+						"pageInfo": map[string]interface{}{
+							"hasNextPage": end < len(jobs),
+							"endCursor":   strconv.Itoa(end),
+						},
+						"nodes": nodes,
+					},
+				},
+			},
+		},
+	})
+}
+
+// writePage writes the requested page of items with GitLab's pagination
+// headers
+func writePage[T any](w http.ResponseWriter, r *http.Request, items []T) {
+	perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+	if perPage <= 0 {
+		perPage = 20
+	}
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page <= 0 {
+		page = 1
+	}
+
+	start := min((page-1)*perPage, len(items))
+	end := min(start+perPage, len(items))
+	totalPages := max((len(items)+perPage-1)/perPage, 1)
+
+	w.Header().Set("X-Total", strconv.Itoa(len(items)))
+	w.Header().Set("X-Total-Pages", strconv.Itoa(totalPages))
+	w.Header().Set("X-Per-Page", strconv.Itoa(perPage))
+	w.Header().Set("X-Page", strconv.Itoa(page))
+	if page < totalPages {
+		w.Header().Set("X-Next-Page", strconv.Itoa(page+1))
+	}
+	writeJSON(w, append([]T{}, items[start:end]...))
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func notFound(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusNotFound)
+	_, _ = w.Write([]byte(`{"message":"404 Not found"}`))
+}
+
+func pathID(r *http.Request, name string) int {
+	id, _ := strconv.Atoi(r.PathValue(name))
+	return id
+}
@@ -0,0 +1,127 @@
+package gitlabtest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+	gitlabpkg "gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/gitlab"
+)
+
+func TestServerPaginatesJobs(t *testing.T) {
+	srv := NewServer(t)
+	for id := 1; id <= 5; id++ {
+		srv.AddJobs(10, &gitlab.Job{ID: id, Name: "job", Status: "success"})
+	}
+
+	cfg := srv.Config()
+	cfg.JobsPerPage = 2
+	client, err := gitlabpkg.NewClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jobs, err := client.FetchJobsByID(context.Background(), "group/project", 10)
+	if err != nil {
+		t.Fatalf("FetchJobsByID failed: %v", err)
+	}
+	if len(jobs) != 5 {
+		t.Fatalf("expected 5 jobs across pages, got %d", len(jobs))
+	}
+	if requests := len(srv.Requests()); requests != 3 {
+		t.Errorf("expected 3 page requests, got %d", requests)
+	}
+}
+
+func TestServerLeavesOutRetriedJobs(t *testing.T) {
+	srv := NewServer(t)
+	srv.AddJobs(10,
+		&gitlab.Job{ID: 1, Name: "test", Status: "failed"},
+		&gitlab.Job{ID: 2, Name: "test", Status: "success"},
+	)
+
+	for _, retried := range []bool{false, true} {
+		cfg := srv.Config()
+		cfg.RetriedJobs = retried
+		client, err := gitlabpkg.NewClient(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		jobs, err := client.FetchJobsByID(context.Background(), "group/project", 10)
+		if err != nil {
+			t.Fatalf("FetchJobsByID failed: %v", err)
+		}
+		if want := map[bool]int{false: 1, true: 2}[retried]; len(jobs) != want {
+			t.Errorf("with retried jobs %v expected %d jobs, got %d", retried, want, len(jobs))
+		}
+	}
+}
+
+func TestServerListsPipelines(t *testing.T) {
+	srv := NewServer(t)
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i, status := range []string{"success", "failed", "success"} {
+		updated := base.Add(time.Duration(i) * time.Hour)
+		srv.AddPipeline(&gitlab.Pipeline{ID: 100 + i, ProjectID: 7, Status: status, Ref: "main", UpdatedAt: &updated})
+	}
+	srv.AddPipeline(&gitlab.Pipeline{ID: 200, ProjectID: 8, Status: "success", Ref: "main", UpdatedAt: &base})
+	srv.AddGroup("platform", 7, 8)
+
+	client, err := gitlabpkg.NewClient(srv.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	projects, err := client.ResolveProjects(context.Background(), []string{"7"}, []string{"platform"})
+	if err != nil {
+		t.Fatalf("ResolveProjects failed: %v", err)
+	}
+	if len(projects) != 2 || projects[0] != "7" || projects[1] != "8" {
+		t.Errorf("expected projects [7 8], got %v", projects)
+	}
+
+	after := base.Add(30 * time.Minute)
+	pipelines, err := client.ListPipelines(context.Background(), "7", gitlabpkg.PipelineFilter{UpdatedAfter: &after, Status: "success"})
+	if err != nil {
+		t.Fatalf("ListPipelines failed: %v", err)
+	}
+	if len(pipelines) != 1 || pipelines[0].ID != 102 {
+		t.Errorf("expected only pipeline 102, got %d pipelines", len(pipelines))
+	}
+
+	if _, err := client.FetchPipelineByID(context.Background(), "7", 999); err == nil {
+		t.Error("expected an error for an unknown pipeline")
+	}
+}
+
+func TestServerServesJobNeeds(t *testing.T) {
+	srv := NewServer(t)
+	pipeline := &gitlab.Pipeline{ID: 10, IID: 3, ProjectID: 1, WebURL: srv.URL + "/group/project/-/pipelines/10"}
+	srv.AddPipeline(pipeline)
+	for id := 1; id <= 150; id++ {
+		srv.AddJobs(10, &gitlab.Job{ID: id, Name: fmt.Sprintf("job-%d", id), Status: "success"})
+	}
+	srv.SetNeeds(10, "job-150", "job-1", "job-2")
+
+	client, err := gitlabpkg.NewClient(srv.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	needs, err := client.FetchJobNeeds(context.Background(), &gitlabpkg.PipelineData{Pipeline: pipeline})
+	if err != nil {
+		t.Fatalf("FetchJobNeeds failed: %v", err)
+	}
+	if len(needs) != 150 {
+		t.Errorf("expected the needs of 150 jobs across pages, got %d", len(needs))
+	}
+	if got := needs["job-150"]; len(got) != 2 || got[0] != "job-1" || got[1] != "job-2" {
+		t.Errorf("unexpected needs of job-150: %v", got)
+	}
+
+	missing := &gitlabpkg.PipelineData{Pipeline: &gitlab.Pipeline{ID: 11, IID: 4, WebURL: pipeline.WebURL}}
+	if _, err := client.FetchJobNeeds(context.Background(), missing); err == nil {
+		t.Error("expected an error for an unknown pipeline")
+	}
+}
@@ -0,0 +1,157 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+)
+
+// JobNeeds maps the job names of a pipeline to the names of the jobs they
+// need (needs:)
+type JobNeeds map[string][]string
+
+// Apply sets the needs of every job, resolving each needed job name to the
+// last attempt that finished before the job started, or to the last attempt
+// if none did
+func (n JobNeeds) Apply(jobs []*JobData) {
+	byName := map[string][]*JobData{}
+	for _, job := range jobs {
+		byName[job.Name] = append(byName[job.Name], job)
+	}
+	for _, attempts := range byName {
+		sort.Slice(attempts, func(i, j int) bool { return attempts[i].ID < attempts[j].ID })
+	}
+
+	for _, job := range jobs {
+		job.Needs = nil
+		for _, name := range n[job.Name] {
+			need := JobNeed{Name: name}
+			attempts := byName[name]
+			if len(attempts) > 0 {
+				need.JobID = attempts[len(attempts)-1].ID
+			}
+			for i := len(attempts) - 1; i >= 0 && job.StartedAt != nil; i-- {
+				if finished := attempts[i].FinishedAt; finished != nil && !finished.After(*job.StartedAt) {
+					need.JobID = attempts[i].ID
+					break
+				}
+			}
+			job.Needs = append(job.Needs, need)
+		}
+	}
+}
+
+// jobNeedsQuery reads the needs of the jobs of a pipeline, a page at a time.
+// The REST API does not expose needs.
+const jobNeedsQuery = `query($project: ID!, $iid: ID!, $after: String) {
+  project(fullPath: $project) {
+    pipeline(iid: $iid) {
+      jobs(first: 100, after: $after) {
+        pageInfo { hasNextPage endCursor }
+        nodes { name needs { nodes { name } } }
+      }
+    }
+  }
+}`
+
+type graphQLRequest struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables"`
+}
+
+type graphQLError struct {
+	Message string `json:"message"`
+}
+
+type jobNeedsResponse struct {
+	Data struct {
+		Project *struct {
+			Pipeline *struct {
+				Jobs struct {
+					PageInfo struct {
+						HasNextPage bool   `json:"hasNextPage"`
+						EndCursor   string `json:"endCursor"`
+					} `json:"pageInfo"`
+					Nodes []struct {
+						Name  string `json:"name"`
+						Needs struct {
+							Nodes []struct {
+								Name string `json:"name"`
+							} `json:"nodes"`
+						} `json:"needs"`
+					} `json:"nodes"`
+				} `json:"jobs"`
+			} `json:"pipeline"`
+		} `json:"project"`
+	} `json:"data"`
+	Errors []graphQLError `json:"errors"`
+}
+
+// FetchJobNeeds retrieves the needs of the jobs of a pipeline from the GraphQL
+// API. GraphQL does not accept CI job tokens, so this takes a private token.
+func (c *Client) FetchJobNeeds(ctx context.Context, pipeline *PipelineData) (JobNeeds, error) {
+	project := pipeline.ProjectPath()
+	if project == "" {
+		return nil, fmt.Errorf("pipeline %d has no web URL to find its project path", pipeline.ID)
+	}
+
+	needs := JobNeeds{}
+	variables := map[string]interface{}{
+		"project": project,
+		"iid":     fmt.Sprint(pipeline.IID),
+	}
+	for {
+		var resp jobNeedsResponse
+		if err := c.graphQL(ctx, jobNeedsQuery, variables, &resp); err != nil {
+			return nil, err
+		}
+		if len(resp.Errors) > 0 {
+			return nil, fmt.Errorf("GraphQL query for the needs of pipeline %d failed: %s", pipeline.ID, resp.Errors[0].Message)
+		}
+		if resp.Data.Project == nil || resp.Data.Project.Pipeline == nil {
+			return nil, fmt.Errorf("pipeline %d not found in project %s", pipeline.ID, project)
+		}
+
+		jobs := resp.Data.Project.Pipeline.Jobs
+		for _, job := range jobs.Nodes {
+			// Every attempt of a job lists the same needs
+			if _, ok := needs[job.Name]; ok {
+				continue
+			}
+			names := make([]string, 0, len(job.Needs.Nodes))
+			for _, need := range job.Needs.Nodes {
+				names = append(names, need.Name)
+			}
+			needs[job.Name] = names
+		}
+
+		if !jobs.PageInfo.HasNextPage {
+			return needs, nil
+		}
+		variables["after"] = jobs.PageInfo.EndCursor
+	}
+}
+
+// graphQL posts a query to the GraphQL API of the GitLab server, with the
+// authentication, retries and timeout of the REST client
+func (c *Client) graphQL(ctx context.Context, query string, variables map[string]interface{}, v interface{}) error {
+	req, err := c.client.NewRequest(http.MethodPost, "",
+		&graphQLRequest{Query: query, Variables: variables},
+		[]gitlab.RequestOptionFunc{gitlab.WithContext(ctx)})
+	if err != nil {
+		return err
+	}
+
+	u := c.client.BaseURL()
+	u.Path = strings.TrimSuffix(u.Path, "v4/") + "graphql"
+	u.RawPath = ""
+	req.URL = u
+	req.Host = u.Host
+
+	_, err = c.client.Do(req, v)
+	return err
+}
@@ -0,0 +1,40 @@
+package gitlab
+
+import (
+	"testing"
+	"time"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+)
+
+func TestJobNeedsApplyResolvesAttempts(t *testing.T) {
+	at := func(minute int) *time.Time {
+		t := time.Date(2026, 3, 1, 12, minute, 0, 0, time.UTC)
+		return &t
+	}
+	jobs := newJobData([]*gitlab.Job{
+		{ID: 1, Name: "build", StartedAt: at(0), FinishedAt: at(2)},
+		{ID: 2, Name: "build", StartedAt: at(3), FinishedAt: at(5)},
+		{ID: 3, Name: "test", StartedAt: at(6), FinishedAt: at(8)},
+		// Retried after test ran, so test waited for the second attempt
+		{ID: 4, Name: "build", StartedAt: at(9), FinishedAt: at(10)},
+		{ID: 5, Name: "deploy"},
+	})
+
+	JobNeeds{"test": {"build"}, "deploy": {"build", "lint"}}.Apply(jobs)
+
+	byID := map[int]*JobData{}
+	for _, job := range jobs {
+		byID[job.ID] = job
+	}
+	if needs := byID[3].Needs; len(needs) != 1 || needs[0] != (JobNeed{Name: "build", JobID: 2}) {
+		t.Errorf("expected test to need build attempt 2, got %+v", needs)
+	}
+	want := []JobNeed{{Name: "build", JobID: 4}, {Name: "lint"}}
+	if needs := byID[5].Needs; len(needs) != 2 || needs[0] != want[0] || needs[1] != want[1] {
+		t.Errorf("expected deploy to need the last build and a missing lint, got %+v", needs)
+	}
+	if needs := byID[1].Needs; needs != nil {
+		t.Errorf("expected build to need nothing, got %+v", needs)
+	}
+}
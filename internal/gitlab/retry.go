@@ -0,0 +1,89 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+// retryWaitMin is the wait before the first retry of a request that GitLab
+// gave no wait time for. Later retries double it, up to the configured
+// maximum.
+const retryWaitMin = 500 * time.Millisecond
+
+// retryPolicy decides whether and when failed GitLab API requests are retried
+type retryPolicy struct {
+	retries int
+	waitMax time.Duration
+}
+
+// checkRetry retries rate-limited (429) and failed (5xx) responses and
+// network errors, unless the context is done. A rate limit asking for a
+// longer wait than waitMax fails the request at once rather than hanging.
+func (p retryPolicy) checkRetry(ctx context.Context, resp *http.Response, err error) (bool, error) {
+	retry, checkErr := retryablehttp.DefaultRetryPolicy(ctx, resp, err)
+	if !retry || resp == nil {
+		return retry, checkErr
+	}
+	if wait := retryAfter(resp, time.Now()); wait > p.waitMax {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		return false, fmt.Errorf("%s %s: GitLab asks to wait %s before retrying, longer than gitlab-retry-wait-max (%s)",
+			resp.Request.Method, resp.Request.URL.Path, wait.Round(time.Second), p.waitMax)
+	}
+	return true, nil
+}
+
+// backoff waits as long as GitLab asks for, or else doubles the wait from
+// waitMin on every attempt, with jitter, up to waitMax
+func (p retryPolicy) backoff(waitMin, waitMax time.Duration, attemptNum int, resp *http.Response) time.Duration {
+	wait := time.Duration(0)
+	if resp != nil {
+		wait = retryAfter(resp, time.Now())
+	}
+	if wait == 0 {
+		wait = time.Duration(float64(waitMin) * math.Pow(2, float64(attemptNum)))
+		wait += time.Duration(rand.Int63n(int64(waitMin)/2 + 1))
+	}
+	wait = min(wait, waitMax)
+
+	reason := "network error"
+	if resp != nil {
+		reason = resp.Status
+	}
+	log.Printf("GitLab API request failed (%s), retry %d of %d in %s", reason, attemptNum+1, p.retries, wait.Round(time.Millisecond))
+	return wait
+}
+
+// retryAfter returns how long GitLab asks to wait before the next request,
+// from the Retry-After header in seconds or as an HTTP date, or else from
+// the RateLimit-Reset header as a Unix time. GitLab sends the RateLimit
+// headers on every response, so the reset only counts when the request was
+// rate limited or no requests remain. It is 0 when GitLab asks for no wait.
+func retryAfter(resp *http.Response, now time.Time) time.Duration {
+	if v := resp.Header.Get("Retry-After"); v != "" {
+		if seconds, err := strconv.Atoi(v); err == nil && seconds > 0 {
+			return time.Duration(seconds) * time.Second
+		}
+		if at, err := http.ParseTime(v); err == nil && at.After(now) {
+			return at.Sub(now)
+		}
+	}
+	limited := resp.StatusCode == http.StatusTooManyRequests || resp.Header.Get("RateLimit-Remaining") == "0"
+	if v := resp.Header.Get("RateLimit-Reset"); v != "" && limited {
+		if reset, err := strconv.ParseInt(v, 10, 64); err == nil {
+			if at := time.Unix(reset, 0); at.After(now) {
+				return at.Sub(now)
+			}
+		}
+	}
+	return 0
+}
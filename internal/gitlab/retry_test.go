@@ -0,0 +1,156 @@
+package gitlab
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/config"
+)
+
+// newFlakyServer answers the first failures requests with status and
+// headers, then serves a pipeline
+func newFlakyServer(t *testing.T, failures int, status int, headers map[string]string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if int(requests.Add(1)) <= failures {
+			for key, value := range headers {
+				w.Header().Set(key, value)
+			}
+			w.WriteHeader(status)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id": 2, "project_id": 1, "status": "success"}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func newRetryingClient(t *testing.T, serverURL string, retries int, waitMax time.Duration) *Client {
+	t.Helper()
+	cfg := config.Default()
+	cfg.ServerURL = serverURL
+	cfg.GitLabRetries = retries
+	cfg.GitLabRetryWaitMax = waitMax
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	return client
+}
+
+func TestClientRetriesServerErrors(t *testing.T) {
+	server, requests := newFlakyServer(t, 2, http.StatusBadGateway, nil)
+	client := newRetryingClient(t, server.URL, 3, 10*time.Millisecond)
+
+	pipeline, err := client.FetchPipelineByID(context.Background(), "1", 2)
+	if err != nil {
+		t.Fatalf("expected the request to succeed after retries, got %v", err)
+	}
+	if pipeline.ID != 2 || requests.Load() != 3 {
+		t.Errorf("expected pipeline 2 after 3 requests, got pipeline %d after %d", pipeline.ID, requests.Load())
+	}
+}
+
+func TestClientGivesUpAfterRetries(t *testing.T) {
+	server, requests := newFlakyServer(t, 10, http.StatusServiceUnavailable, nil)
+	client := newRetryingClient(t, server.URL, 2, 10*time.Millisecond)
+
+	_, err := client.FetchPipelineByID(context.Background(), "1", 2)
+	if err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("expected the 503 to be reported, got %v", err)
+	}
+	if requests.Load() != 3 {
+		t.Errorf("expected 1 request and 2 retries, got %d requests", requests.Load())
+	}
+}
+
+func TestClientFailsFastOnLongRateLimit(t *testing.T) {
+	server, requests := newFlakyServer(t, 1, http.StatusTooManyRequests, map[string]string{"Retry-After": "3600"})
+	client := newRetryingClient(t, server.URL, 5, time.Second)
+
+	start := time.Now()
+	_, err := client.FetchPipelineByID(context.Background(), "1", 2)
+	if err == nil || !strings.Contains(err.Error(), "gitlab-retry-wait-max") {
+		t.Errorf("expected the rate limit wait to exceed gitlab-retry-wait-max, got %v", err)
+	}
+	if requests.Load() != 1 || time.Since(start) > time.Second {
+		t.Errorf("expected a single request failing at once, got %d requests in %s", requests.Load(), time.Since(start))
+	}
+}
+
+func TestClientBacksOffServerErrorsDespiteRateLimitHeaders(t *testing.T) {
+	// GitLab sends the RateLimit headers on every response, not only when
+	// the request was rate limited
+	reset := strconv.FormatInt(time.Now().Add(50*time.Second).Unix(), 10)
+	server, requests := newFlakyServer(t, 1, http.StatusBadGateway, map[string]string{"RateLimit-Remaining": "1999", "RateLimit-Reset": reset})
+	client := newRetryingClient(t, server.URL, 3, 5*time.Second)
+
+	start := time.Now()
+	if _, err := client.FetchPipelineByID(context.Background(), "1", 2); err != nil {
+		t.Fatalf("expected the request to succeed after a retry, got %v", err)
+	}
+	if requests.Load() != 2 || time.Since(start) > 5*time.Second {
+		t.Errorf("expected a quick retry, got %d requests in %s", requests.Load(), time.Since(start))
+	}
+}
+
+func TestClientTimesOutRequests(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	cfg := config.Default()
+	cfg.ServerURL = server.URL
+	cfg.GitLabRetries = 0
+	cfg.GitLabTimeout = 50 * time.Millisecond
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.FetchPipelineByID(context.Background(), "1", 2); err == nil {
+		t.Error("expected the request to time out")
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		status  int
+		headers map[string]string
+		want    time.Duration
+	}{
+		{"none", http.StatusTooManyRequests, nil, 0},
+		{"seconds", http.StatusServiceUnavailable, map[string]string{"Retry-After": "7"}, 7 * time.Second},
+		{"http date", http.StatusTooManyRequests, map[string]string{"Retry-After": now.Add(time.Minute).Format(http.TimeFormat)}, time.Minute},
+		{"rate limit reset", http.StatusTooManyRequests, map[string]string{"RateLimit-Reset": strconv.FormatInt(now.Add(30*time.Second).Unix(), 10)}, 30 * time.Second},
+		{"no requests remaining", http.StatusBadGateway, map[string]string{"RateLimit-Remaining": "0", "RateLimit-Reset": strconv.FormatInt(now.Add(30*time.Second).Unix(), 10)}, 30 * time.Second},
+		{"reset of a server error", http.StatusBadGateway, map[string]string{"RateLimit-Remaining": "1999", "RateLimit-Reset": strconv.FormatInt(now.Add(30*time.Second).Unix(), 10)}, 0},
+		{"reset in the past", http.StatusTooManyRequests, map[string]string{"RateLimit-Reset": strconv.FormatInt(now.Add(-time.Minute).Unix(), 10)}, 0},
+		{"retry-after first", http.StatusTooManyRequests, map[string]string{"Retry-After": "2", "RateLimit-Reset": strconv.FormatInt(now.Add(time.Hour).Unix(), 10)}, 2 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+			for key, value := range tt.headers {
+				resp.Header.Set(key, value)
+			}
+			if got := retryAfter(resp, now); got != tt.want {
+				t.Errorf("retryAfter() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package gitlab
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/utils"
+)
+
+// sectionRegex matches section markers in GitLab job logs, e.g.
+// section_start:1560896352:step_script[collapsed=true]
+var sectionRegex = regexp.MustCompile(`section_(start|end):(\d+):([A-Za-z0-9_.-]+)(?:\[([^\]]*)\])?`)
+
+// Section is a timed section of a job log, as delimited by GitLab's
+// section_start / section_end markers
+type Section struct {
+	Name      string
+	Start     time.Time
+	End       time.Time
+	Collapsed bool
+	Children  []*Section
+}
+
+// ParseSections extracts the (possibly nested) sections of a job log.
+// Sections left open at the end of the log are closed at the last timestamp
+// seen, and section_end markers without a matching start are ignored.
+func ParseSections(r io.Reader) ([]*Section, error) {
+	var roots, stack []*Section
+	var last time.Time
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		for _, m := range sectionRegex.FindAllStringSubmatch(scanner.Text(), -1) {
+			ts, err := strconv.ParseInt(m[2], 10, 64)
+			if err != nil {
+				continue
+			}
+			at := time.Unix(ts, 0)
+			if at.After(last) {
+				last = at
+			}
+
+			switch m[1] {
+			case "start":
+				section := &Section{
+					Name:      m[3],
+					Start:     at,
+					Collapsed: strings.Contains(m[4], "collapsed=true"),
+				}
+				if len(stack) == 0 {
+					roots = append(roots, section)
+				} else {
+					parent := stack[len(stack)-1]
+					parent.Children = append(parent.Children, section)
+				}
+				stack = append(stack, section)
+			case "end":
+				// Close the innermost open section with this name, along
+				// with any sections nested in it that were left open
+				for i := len(stack) - 1; i >= 0; i-- {
+					if stack[i].Name != m[3] {
+						continue
+					}
+					for _, open := range stack[i:] {
+						open.End = at
+					}
+					stack = stack[:i]
+					break
+				}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, open := range stack {
+		open.End = last
+	}
+	return roots, nil
+}
+
+// ParseLogLine cleans a job log line for display, removing section markers,
+// ANSI escape codes and text overwritten by carriage returns. It also returns
+// the timestamp of the last section marker on the line, if any.
+func ParseLogLine(line string) (string, time.Time, bool) {
+	var at time.Time
+	markers := sectionRegex.FindAllStringSubmatch(line, -1)
+	if len(markers) > 0 {
+		if ts, err := strconv.ParseInt(markers[len(markers)-1][2], 10, 64); err == nil {
+			at = time.Unix(ts, 0)
+		}
+		line = sectionRegex.ReplaceAllString(line, "")
+	}
+
+	line = utils.StripANSI(strings.TrimRight(line, "\r"))
+	if i := strings.LastIndex(line, "\r"); i >= 0 {
+		line = line[i+1:]
+	}
+	return line, at, !at.IsZero()
+}
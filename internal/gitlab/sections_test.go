@@ -0,0 +1,85 @@
+package gitlab
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSections(t *testing.T) {
+	log := strings.Join([]string{
+		"\x1b[0Ksection_start:1000:prepare_executor\r\x1b[0KPreparing the executor",
+		"Using docker executor",
+		"\x1b[0Ksection_end:1005:prepare_executor\r\x1b[0K",
+		"\x1b[0Ksection_start:1005:step_script[collapsed=true]\r\x1b[0KExecuting step_script",
+		"\x1b[0Ksection_start:1006:install_deps\r\x1b[0K$ npm ci",
+		"\x1b[0Ksection_end:1020:install_deps\r\x1b[0K",
+		"\x1b[0Ksection_end:1030:step_script\r\x1b[0K",
+		"\x1b[0Ksection_end:1031:unknown\r\x1b[0K",
+		"\x1b[0Ksection_start:1031:upload_artifacts\r\x1b[0KUploading artifacts",
+		"\x1b[0Ksection_start:1032:upload_inner\r\x1b[0K",
+		"done at 1040",
+		"\x1b[0Ksection_end:1040:upload_artifacts\r\x1b[0K",
+	}, "\n")
+
+	sections, err := ParseSections(strings.NewReader(log))
+	if err != nil {
+		t.Fatalf("ParseSections failed: %v", err)
+	}
+	if len(sections) != 3 {
+		t.Fatalf("expected 3 top-level sections, got %d", len(sections))
+	}
+
+	prepare := sections[0]
+	if prepare.Name != "prepare_executor" || !prepare.Start.Equal(time.Unix(1000, 0)) || !prepare.End.Equal(time.Unix(1005, 0)) {
+		t.Errorf("unexpected prepare_executor section: %+v", prepare)
+	}
+
+	script := sections[1]
+	if !script.Collapsed {
+		t.Error("step_script should be collapsed")
+	}
+	if len(script.Children) != 1 || script.Children[0].Name != "install_deps" {
+		t.Fatalf("step_script should contain install_deps, got %+v", script.Children)
+	}
+	if !script.Children[0].End.Equal(time.Unix(1020, 0)) {
+		t.Errorf("install_deps should end at 1020, got %v", script.Children[0].End)
+	}
+
+	upload := sections[2]
+	if len(upload.Children) != 1 || !upload.Children[0].End.Equal(time.Unix(1040, 0)) {
+		t.Errorf("unclosed nested section should be closed with its parent, got %+v", upload.Children)
+	}
+}
+
+func TestParseSectionsUnclosed(t *testing.T) {
+	log := "section_start:1000:step_script\r\nsection_start:1010:inner\r\n"
+
+	sections, err := ParseSections(strings.NewReader(log))
+	if err != nil {
+		t.Fatalf("ParseSections failed: %v", err)
+	}
+	if len(sections) != 1 || !sections[0].End.Equal(time.Unix(1010, 0)) {
+		t.Errorf("unclosed section should end at last timestamp, got %+v", sections)
+	}
+}
+
+func TestParseLogLine(t *testing.T) {
+	tests := []struct {
+		line       string
+		want       string
+		wantMarker bool
+	}{
+		{"\x1b[0Ksection_start:1000:step_script\r\x1b[0K\x1b[36;1mExecuting step_script\x1b[0;m", "Executing step_script", true},
+		{"\x1b[32;1m$ make test\x1b[0;m\r", "$ make test", false},
+		{"Downloading 10%\rDownloading 100%", "Downloading 100%", false},
+		{"section_end:1010:step_script\r\x1b[0K", "", true},
+	}
+
+	for _, tt := range tests {
+		got, _, marker := ParseLogLine(tt.line)
+		if got != tt.want || marker != tt.wantMarker {
+			t.Errorf("ParseLogLine(%q) = %q, %v, want %q, %v", tt.line, got, marker, tt.want, tt.wantMarker)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package gitlab
+
+import (
+	"context"
+	"io"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+)
+
+// PipelineLister finds the pipelines of projects and groups. It is
+// implemented by Client.
+type PipelineLister interface {
+	ListPipelines(ctx context.Context, projectID string, filter PipelineFilter) ([]*gitlab.PipelineInfo, error)
+	ResolveProjects(ctx context.Context, projects, groups []string) ([]string, error)
+}
+
+// PipelineSource provides the GitLab data a pipeline trace is built from. It
+// is implemented by Client, which reads the GitLab API, and by FileSource,
+// which reads API responses saved by a Recorder.
+type PipelineSource interface {
+	FetchPipelineByID(ctx context.Context, projectID string, pipelineID int) (*PipelineData, error)
+	FetchJobsByID(ctx context.Context, projectID string, pipelineID int) ([]*JobData, error)
+	FetchBridgesByID(ctx context.Context, projectID string, pipelineID int) ([]*BridgeData, error)
+	FetchPipelineVariables(ctx context.Context, projectID string, pipelineID int) ([]*gitlab.PipelineVariable, error)
+	FetchJobTrace(ctx context.Context, projectID string, jobID int) (io.Reader, error)
+	FetchJobNeeds(ctx context.Context, pipeline *PipelineData) (JobNeeds, error)
+}
@@ -1,6 +1,28 @@
 package gitlab
 
-import gitlab "gitlab.com/gitlab-org/api/client-go"
+import (
+	"log"
+	"net/url"
+	"sort"
+	"strings"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/utils"
+)
+
+// terminalStatuses are the pipeline and job statuses that no longer change,
+// unless a job is retried
+var terminalStatuses = map[string]bool{
+	"success":  true,
+	"failed":   true,
+	"canceled": true,
+	"skipped":  true,
+}
+
+// IsTerminalStatus reports whether a pipeline or job status is final
+func IsTerminalStatus(status string) bool {
+	return terminalStatuses[status]
+}
 
 // PipelineData wraps GitLab pipeline with raw data
 type PipelineData struct {
@@ -8,8 +30,108 @@ type PipelineData struct {
 	Raw map[string]interface{}
 }
 
+// ProjectURL returns the web URL of the project the pipeline belongs to
+func (p *PipelineData) ProjectURL() string {
+	if i := strings.Index(p.WebURL, "/-/"); i >= 0 {
+		return p.WebURL[:i]
+	}
+	return ""
+}
+
+// ProjectPath returns the full namespace path of the project the pipeline
+// belongs to (e.g. group/subgroup/project), derived from its web URL
+func (p *PipelineData) ProjectPath() string {
+	u, err := url.Parse(p.ProjectURL())
+	if err != nil {
+		return ""
+	}
+	return strings.Trim(u.Path, "/")
+}
+
 // JobData wraps GitLab job with raw data
 type JobData struct {
 	*gitlab.Job
 	Raw map[string]interface{}
+
+	// Attempt numbers the runs of a job name in a pipeline from 1. Retried
+	// is set on every attempt but the last, and PreviousAttemptID is the ID
+	// of the attempt before, or 0 for the first.
+	Attempt           int
+	Retried           bool
+	PreviousAttemptID int
+
+	// Needs are the jobs this job needs (needs:), set by JobNeeds.Apply
+	// when job-needs is enabled
+	Needs []JobNeed
+}
+
+// JobNeed is a job another job needs, resolved to the attempt it waited
+// for. JobID is 0 when no attempt of the job is among the pipeline's jobs.
+type JobNeed struct {
+	Name  string
+	JobID int
+}
+
+// BridgeData wraps GitLab bridge (trigger job) with raw data
+type BridgeData struct {
+	*gitlab.Bridge
+	Raw map[string]interface{}
+}
+
+func newPipelineData(pipeline *gitlab.Pipeline) (*PipelineData, error) {
+	raw, err := utils.StructToMap(pipeline)
+	if err != nil {
+		return nil, err
+	}
+	utils.CleanRaw(raw)
+
+	return &PipelineData{Pipeline: pipeline, Raw: raw}, nil
+}
+
+func newJobData(jobs []*gitlab.Job) []*JobData {
+	var jobData []*JobData
+	for _, job := range jobs {
+		raw, err := utils.StructToMap(job)
+		if err != nil {
+			log.Printf("failed to convert job %d to map: %v", job.ID, err)
+			continue
+		}
+		utils.CleanRaw(raw)
+		jobData = append(jobData, &JobData{Job: job, Raw: raw})
+	}
+	assignAttempts(jobData)
+	return jobData
+}
+
+// assignAttempts numbers the runs of each job name. GitLab gives a retry a
+// new job ID, and IDs grow in creation order.
+func assignAttempts(jobs []*JobData) {
+	byName := map[string][]*JobData{}
+	for _, job := range jobs {
+		byName[job.Name] = append(byName[job.Name], job)
+	}
+	for _, attempts := range byName {
+		sort.Slice(attempts, func(i, j int) bool { return attempts[i].ID < attempts[j].ID })
+		for i, job := range attempts {
+			job.Attempt = i + 1
+			job.Retried = i < len(attempts)-1
+			if i > 0 {
+				job.PreviousAttemptID = attempts[i-1].ID
+			}
+		}
+	}
+}
+
+func newBridgeData(bridges []*gitlab.Bridge) []*BridgeData {
+	var bridgeData []*BridgeData
+	for _, bridge := range bridges {
+		raw, err := utils.StructToMap(bridge)
+		if err != nil {
+			log.Printf("failed to convert bridge %d to map: %v", bridge.ID, err)
+			continue
+		}
+		utils.CleanRaw(raw)
+		bridgeData = append(bridgeData, &BridgeData{Bridge: bridge, Raw: raw})
+	}
+	return bridgeData
 }
@@ -0,0 +1,56 @@
+package gitlab
+
+import (
+	"testing"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+)
+
+func TestPipelineProjectPath(t *testing.T) {
+	tests := []struct {
+		webURL  string
+		wantURL string
+		want    string
+	}{
+		{"https://gitlab.com/group/sub/project/-/pipelines/123", "https://gitlab.com/group/sub/project", "group/sub/project"},
+		{"https://gitlab.com/project/-/pipelines/1", "https://gitlab.com/project", "project"},
+		{"", "", ""},
+	}
+
+	for _, tt := range tests {
+		pipeline := &PipelineData{Pipeline: &gitlab.Pipeline{WebURL: tt.webURL}}
+		if got := pipeline.ProjectURL(); got != tt.wantURL {
+			t.Errorf("ProjectURL() for %q = %q, want %q", tt.webURL, got, tt.wantURL)
+		}
+		if got := pipeline.ProjectPath(); got != tt.want {
+			t.Errorf("ProjectPath() for %q = %q, want %q", tt.webURL, got, tt.want)
+		}
+	}
+}
+
+func TestNewJobDataNumbersAttempts(t *testing.T) {
+	jobs := newJobData([]*gitlab.Job{
+		{ID: 15, Name: "test"},
+		{ID: 11, Name: "build"},
+		{ID: 12, Name: "test"},
+		{ID: 10, Name: "test"},
+	})
+
+	want := map[int]struct {
+		attempt  int
+		retried  bool
+		previous int
+	}{
+		10: {1, true, 0},
+		12: {2, true, 10},
+		15: {3, false, 12},
+		11: {1, false, 0},
+	}
+	for _, job := range jobs {
+		w := want[job.ID]
+		if job.Attempt != w.attempt || job.Retried != w.retried || job.PreviousAttemptID != w.previous {
+			t.Errorf("job %d: got attempt %d, retried %v, previous %d; want %d, %v, %d",
+				job.ID, job.Attempt, job.Retried, job.PreviousAttemptID, w.attempt, w.retried, w.previous)
+		}
+	}
+}
@@ -2,32 +2,266 @@ package otel
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"os"
+	"strings"
+	"time"
 
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploggrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutlog"
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutmetric"
 	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"google.golang.org/grpc/credentials"
+	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/config"
 )
 
+// Connection holds how the OTLP exporters connect to the collector
+type Connection struct {
+	Headers     map[string]string
+	Timeout     time.Duration
+	Compression string
+
+	// Insecure disables TLS for endpoints given as host:port. The scheme of
+	// an endpoint URL takes precedence.
+	Insecure bool
+	TLS      *tls.Config
+}
+
+// NewConnection builds the collector connection settings from cfg, loading
+// the configured CA and client certificates
+func NewConnection(cfg *config.Config) (Connection, error) {
+	headers, err := cfg.OTLPHeaders()
+	if err != nil {
+		return Connection{}, err
+	}
+
+	conn := Connection{
+		Headers:     headers,
+		Timeout:     time.Duration(cfg.Timeout) * time.Millisecond,
+		Compression: cfg.Compression,
+		Insecure:    cfg.Insecure,
+		TLS:         &tls.Config{MinVersion: tls.VersionTLS12},
+	}
+	if cfg.Certificate != "" {
+		pem, err := os.ReadFile(cfg.Certificate)
+		if err != nil {
+			return Connection{}, fmt.Errorf("failed to read OTLP certificate: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return Connection{}, fmt.Errorf("no PEM certificates found in %s", cfg.Certificate)
+		}
+		conn.TLS.RootCAs = pool
+	}
+	if cfg.ClientCertificate != "" || cfg.ClientKey != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCertificate, cfg.ClientKey)
+		if err != nil {
+			return Connection{}, fmt.Errorf("failed to load OTLP client certificate: %w", err)
+		}
+		conn.TLS.Certificates = []tls.Certificate{cert}
+	}
+
+	return conn, nil
+}
+
+// isURL reports whether an endpoint is a URL rather than host:port
+func isURL(endpoint string) bool {
+	return strings.Contains(endpoint, "://")
+}
+
+// insecure reports whether the connection to endpoint skips TLS
+func (conn Connection) insecure(endpoint string) bool {
+	if isURL(endpoint) {
+		return strings.HasPrefix(endpoint, "http://")
+	}
+	return conn.Insecure
+}
+
+func (conn Connection) gzip() bool {
+	return conn.Compression == "gzip"
+}
+
 // CreateExporter creates an OTLP exporter based on protocol
-func CreateExporter(ctx context.Context, protocol, endpoint string) (sdktrace.SpanExporter, error) {
+func CreateExporter(ctx context.Context, protocol, endpoint string, conn Connection) (sdktrace.SpanExporter, error) {
 	switch protocol {
-	case "http":
-		return otlptracehttp.New(ctx,
-			otlptracehttp.WithEndpoint(endpoint),
-			otlptracehttp.WithInsecure(),
+	case "http", "http/protobuf", "grpc":
+		client, err := CreateTraceClient(protocol, endpoint, conn)
+		if err != nil {
+			return nil, err
+		}
+		return otlptrace.New(ctx, client)
+	case "stdout", "console":
+		return stdouttrace.New(
+			stdouttrace.WithPrettyPrint(),
 		)
+	case "file":
+		return NewFileExporter(endpoint)
+	default:
+		return nil, unsupportedProtocol(protocol)
+	}
+}
+
+// CreateTraceClient creates the OTLP client sending traces to a collector,
+// used by the trace exporter and to replay OTLP files
+func CreateTraceClient(protocol, endpoint string, conn Connection) (otlptrace.Client, error) {
+	switch protocol {
+	case "http", "http/protobuf":
+		opts := []otlptracehttp.Option{otlptracehttp.WithHeaders(conn.Headers)}
+		if isURL(endpoint) {
+			opts = append(opts, otlptracehttp.WithEndpointURL(endpoint))
+		} else {
+			opts = append(opts, otlptracehttp.WithEndpoint(endpoint))
+		}
+		if conn.insecure(endpoint) {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		} else {
+			opts = append(opts, otlptracehttp.WithTLSClientConfig(conn.TLS))
+		}
+		if conn.Timeout > 0 {
+			opts = append(opts, otlptracehttp.WithTimeout(conn.Timeout))
+		}
+		if conn.gzip() {
+			opts = append(opts, otlptracehttp.WithCompression(otlptracehttp.GzipCompression))
+		}
+		return otlptracehttp.NewClient(opts...), nil
 	case "grpc":
-		return otlptracegrpc.New(ctx,
-			otlptracegrpc.WithEndpoint(endpoint),
-			otlptracegrpc.WithInsecure(),
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithHeaders(conn.Headers)}
+		if isURL(endpoint) {
+			opts = append(opts, otlptracegrpc.WithEndpointURL(endpoint))
+		} else {
+			opts = append(opts, otlptracegrpc.WithEndpoint(endpoint))
+		}
+		if conn.insecure(endpoint) {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		} else {
+			opts = append(opts, otlptracegrpc.WithTLSCredentials(credentials.NewTLS(conn.TLS)))
+		}
+		if conn.Timeout > 0 {
+			opts = append(opts, otlptracegrpc.WithTimeout(conn.Timeout))
+		}
+		if conn.gzip() {
+			opts = append(opts, otlptracegrpc.WithCompressor("gzip"))
+		}
+		return otlptracegrpc.NewClient(opts...), nil
+	default:
+		return nil, fmt.Errorf("unsupported protocol: %s (an OTLP collector is reached with http/protobuf or grpc)", protocol)
+	}
+}
+
+// CreateLogExporter creates an OTLP log exporter based on protocol
+func CreateLogExporter(ctx context.Context, protocol, endpoint string, conn Connection) (sdklog.Exporter, error) {
+	switch protocol {
+	case "http", "http/protobuf":
+		opts := []otlploghttp.Option{otlploghttp.WithHeaders(conn.Headers)}
+		if isURL(endpoint) {
+			opts = append(opts, otlploghttp.WithEndpointURL(endpoint))
+		} else {
+			opts = append(opts, otlploghttp.WithEndpoint(endpoint))
+		}
+		if conn.insecure(endpoint) {
+			opts = append(opts, otlploghttp.WithInsecure())
+		} else {
+			opts = append(opts, otlploghttp.WithTLSClientConfig(conn.TLS))
+		}
+		if conn.Timeout > 0 {
+			opts = append(opts, otlploghttp.WithTimeout(conn.Timeout))
+		}
+		if conn.gzip() {
+			opts = append(opts, otlploghttp.WithCompression(otlploghttp.GzipCompression))
+		}
+		return otlploghttp.New(ctx, opts...)
+	case "grpc":
+		opts := []otlploggrpc.Option{otlploggrpc.WithHeaders(conn.Headers)}
+		if isURL(endpoint) {
+			opts = append(opts, otlploggrpc.WithEndpointURL(endpoint))
+		} else {
+			opts = append(opts, otlploggrpc.WithEndpoint(endpoint))
+		}
+		if conn.insecure(endpoint) {
+			opts = append(opts, otlploggrpc.WithInsecure())
+		} else {
+			opts = append(opts, otlploggrpc.WithTLSCredentials(credentials.NewTLS(conn.TLS)))
+		}
+		if conn.Timeout > 0 {
+			opts = append(opts, otlploggrpc.WithTimeout(conn.Timeout))
+		}
+		if conn.gzip() {
+			opts = append(opts, otlploggrpc.WithCompressor("gzip"))
+		}
+		return otlploggrpc.New(ctx, opts...)
+	case "stdout", "console":
+		return stdoutlog.New(
+			stdoutlog.WithPrettyPrint(),
 		)
+	default:
+		return nil, unsupportedProtocol(protocol)
+	}
+}
+
+// CreateMetricExporter creates an OTLP metric exporter based on protocol
+func CreateMetricExporter(ctx context.Context, protocol, endpoint string, conn Connection) (sdkmetric.Exporter, error) {
+	switch protocol {
+	case "http", "http/protobuf":
+		opts := []otlpmetrichttp.Option{otlpmetrichttp.WithHeaders(conn.Headers)}
+		if isURL(endpoint) {
+			opts = append(opts, otlpmetrichttp.WithEndpointURL(endpoint))
+		} else {
+			opts = append(opts, otlpmetrichttp.WithEndpoint(endpoint))
+		}
+		if conn.insecure(endpoint) {
+			opts = append(opts, otlpmetrichttp.WithInsecure())
+		} else {
+			opts = append(opts, otlpmetrichttp.WithTLSClientConfig(conn.TLS))
+		}
+		if conn.Timeout > 0 {
+			opts = append(opts, otlpmetrichttp.WithTimeout(conn.Timeout))
+		}
+		if conn.gzip() {
+			opts = append(opts, otlpmetrichttp.WithCompression(otlpmetrichttp.GzipCompression))
+		}
+		return otlpmetrichttp.New(ctx, opts...)
+	case "grpc":
+		opts := []otlpmetricgrpc.Option{otlpmetricgrpc.WithHeaders(conn.Headers)}
+		if isURL(endpoint) {
+			opts = append(opts, otlpmetricgrpc.WithEndpointURL(endpoint))
+		} else {
+			opts = append(opts, otlpmetricgrpc.WithEndpoint(endpoint))
+		}
+		if conn.insecure(endpoint) {
+			opts = append(opts, otlpmetricgrpc.WithInsecure())
+		} else {
+			opts = append(opts, otlpmetricgrpc.WithTLSCredentials(credentials.NewTLS(conn.TLS)))
+		}
+		if conn.Timeout > 0 {
+			opts = append(opts, otlpmetricgrpc.WithTimeout(conn.Timeout))
+		}
+		if conn.gzip() {
+			opts = append(opts, otlpmetricgrpc.WithCompressor("gzip"))
+		}
+		return otlpmetricgrpc.New(ctx, opts...)
 	case "stdout", "console":
-		return stdouttrace.New(
-			stdouttrace.WithPrettyPrint(),
+		return stdoutmetric.New(
+			stdoutmetric.WithPrettyPrint(),
 		)
 	default:
-		return nil, fmt.Errorf("unsupported protocol: %s (supported: http, grpc, stdout)", protocol)
+		return nil, unsupportedProtocol(protocol)
+	}
+}
+
+func unsupportedProtocol(protocol string) error {
+	if protocol == "http/json" {
+		return fmt.Errorf("unsupported protocol: http/json is not supported by the OpenTelemetry Go SDK (supported: http/protobuf, grpc, stdout)")
 	}
+	return fmt.Errorf("unsupported protocol: %s (supported: http/protobuf, grpc, stdout)", protocol)
 }
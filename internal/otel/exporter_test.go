@@ -2,14 +2,25 @@ package otel
 
 import (
 	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/config"
 )
 
+var insecure = Connection{Insecure: true}
+
 func TestCreateExporter(t *testing.T) {
 	ctx := context.Background()
 
 	// Test HTTP exporter
-	exporter, err := CreateExporter(ctx, "http", "localhost:4318")
+	exporter, err := CreateExporter(ctx, "http", "localhost:4318", insecure)
 	if err != nil {
 		t.Errorf("HTTP exporter creation failed: %v", err)
 	}
@@ -18,7 +29,7 @@ func TestCreateExporter(t *testing.T) {
 	}
 
 	// Test gRPC exporter
-	exporter, err = CreateExporter(ctx, "grpc", "localhost:4317")
+	exporter, err = CreateExporter(ctx, "grpc", "localhost:4317", insecure)
 	if err != nil {
 		t.Errorf("gRPC exporter creation failed: %v", err)
 	}
@@ -27,7 +38,7 @@ func TestCreateExporter(t *testing.T) {
 	}
 
 	// Test stdout exporter
-	exporter, err = CreateExporter(ctx, "stdout", "stdout")
+	exporter, err = CreateExporter(ctx, "stdout", "stdout", insecure)
 	if err != nil {
 		t.Errorf("stdout exporter creation failed: %v", err)
 	}
@@ -36,7 +47,7 @@ func TestCreateExporter(t *testing.T) {
 	}
 
 	// Test console alias
-	exporter, err = CreateExporter(ctx, "console", "stdout")
+	exporter, err = CreateExporter(ctx, "console", "stdout", insecure)
 	if err != nil {
 		t.Errorf("console exporter creation failed: %v", err)
 	}
@@ -45,7 +56,7 @@ func TestCreateExporter(t *testing.T) {
 	}
 
 	// Test unsupported protocol
-	exporter, err = CreateExporter(ctx, "invalid", "localhost:4318")
+	exporter, err = CreateExporter(ctx, "invalid", "localhost:4318", insecure)
 	if err == nil {
 		t.Error("invalid protocol should return error")
 	}
@@ -53,3 +64,162 @@ func TestCreateExporter(t *testing.T) {
 		t.Error("invalid protocol exporter should be nil")
 	}
 }
+
+func TestCreateLogExporter(t *testing.T) {
+	ctx := context.Background()
+
+	for _, protocol := range []string{"http", "grpc", "stdout", "console"} {
+		exporter, err := CreateLogExporter(ctx, protocol, "localhost:4318", insecure)
+		if err != nil {
+			t.Errorf("%s log exporter creation failed: %v", protocol, err)
+		}
+		if exporter == nil {
+			t.Errorf("%s log exporter should not be nil", protocol)
+		}
+	}
+
+	exporter, err := CreateLogExporter(ctx, "invalid", "localhost:4318", insecure)
+	if err == nil {
+		t.Error("invalid protocol should return error")
+	}
+	if exporter != nil {
+		t.Error("invalid protocol log exporter should be nil")
+	}
+}
+
+func TestCreateMetricExporter(t *testing.T) {
+	ctx := context.Background()
+
+	for _, protocol := range []string{"http", "grpc", "stdout", "console"} {
+		exporter, err := CreateMetricExporter(ctx, protocol, "localhost:4318", insecure)
+		if err != nil {
+			t.Errorf("%s metric exporter creation failed: %v", protocol, err)
+		}
+		if exporter == nil {
+			t.Errorf("%s metric exporter should not be nil", protocol)
+		}
+	}
+
+	exporter, err := CreateMetricExporter(ctx, "invalid", "localhost:4318", insecure)
+	if err == nil {
+		t.Error("invalid protocol should return error")
+	}
+	if exporter != nil {
+		t.Error("invalid protocol metric exporter should be nil")
+	}
+}
+
+func TestCreateExporterUsesTLSAndHeaders(t *testing.T) {
+	received := make(chan http.Header, 1)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case received <- r.Header.Clone():
+		default:
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	// Trust the test server through a CA file, as a collector's CA would be
+	ca := filepath.Join(t.TempDir(), "ca.pem")
+	pemCert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(ca, pemCert, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := NewConnection(&config.Config{
+		Headers:     "x-api-key=secret",
+		Certificate: ca,
+	})
+	if err != nil {
+		t.Fatalf("NewConnection failed: %v", err)
+	}
+
+	ctx := context.Background()
+	exporter, err := CreateExporter(ctx, "http", strings.TrimPrefix(server.URL, "https://"), conn)
+	if err != nil {
+		t.Fatalf("CreateExporter failed: %v", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	_, span := tp.Tracer("test").Start(ctx, "span")
+	span.End()
+	if err := tp.Shutdown(ctx); err != nil {
+		t.Fatalf("export over TLS failed: %v", err)
+	}
+
+	select {
+	case headers := <-received:
+		if headers.Get("x-api-key") != "secret" {
+			t.Errorf("expected x-api-key header, got %v", headers)
+		}
+	default:
+		t.Error("collector received no export")
+	}
+}
+
+func TestNewConnectionRejectsInvalidCertificate(t *testing.T) {
+	ca := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(ca, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewConnection(&config.Config{Certificate: ca}); err == nil {
+		t.Error("expected an error for a file without PEM certificates")
+	}
+}
+
+func TestCreateExporterUsesEndpointURLAndCompression(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case received <- r:
+		default:
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		Protocol:    "http/protobuf",
+		Endpoint:    server.URL + "/otlp",
+		Timeout:     5000,
+		Compression: "gzip",
+	}
+	conn, err := NewConnection(cfg)
+	if err != nil {
+		t.Fatalf("NewConnection failed: %v", err)
+	}
+
+	ctx := context.Background()
+	exporter, err := CreateExporter(ctx, cfg.Protocol, cfg.SignalEndpoint("traces"), conn)
+	if err != nil {
+		t.Fatalf("CreateExporter failed: %v", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	_, span := tp.Tracer("test").Start(ctx, "span")
+	span.End()
+	if err := tp.Shutdown(ctx); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+
+	select {
+	case r := <-received:
+		if r.URL.Path != "/otlp/v1/traces" {
+			t.Errorf("expected path /otlp/v1/traces, got %s", r.URL.Path)
+		}
+		if r.Header.Get("Content-Encoding") != "gzip" {
+			t.Errorf("expected gzip compression, got %q", r.Header.Get("Content-Encoding"))
+		}
+	default:
+		t.Error("collector received no export")
+	}
+}
+
+func TestCreateExporterRejectsHTTPJSON(t *testing.T) {
+	_, err := CreateExporter(context.Background(), "http/json", "localhost:4318", insecure)
+	if err == nil || !strings.Contains(err.Error(), "http/json") {
+		t.Errorf("expected http/json to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,356 @@
+package otel
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// FileExporter appends spans to a file as OTLP/JSON, one
+// ExportTraceServiceRequest per line as in the OTLP file exporter
+// specification. The files can be sent to a collector later with Replay.
+type FileExporter struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFileExporter creates an exporter appending to path, creating the file
+// if needed
+func NewFileExporter(path string) (*FileExporter, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open OTLP file: %w", err)
+	}
+	return &FileExporter{file: file}, nil
+}
+
+// ExportSpans writes spans as one ExportTraceServiceRequest line
+func (e *FileExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	if len(spans) == 0 {
+		return nil
+	}
+
+	line, err := MarshalTraceRequest(&coltracepb.ExportTraceServiceRequest{ResourceSpans: resourceSpans(spans)})
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.file == nil {
+		return errors.New("OTLP file exporter is shut down")
+	}
+	_, err = e.file.Write(append(line, '\n'))
+	return err
+}
+
+// Shutdown closes the file
+func (e *FileExporter) Shutdown(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.file == nil {
+		return nil
+	}
+	err := e.file.Close()
+	e.file = nil
+	return err
+}
+
+// idFields are the JSON fields that OTLP/JSON encodes as hex rather than the
+// base64 protobuf JSON uses for bytes
+var idFields = map[string]bool{"traceId": true, "spanId": true, "parentSpanId": true}
+
+// MarshalTraceRequest encodes req as OTLP/JSON: trace and span IDs in hex and
+// enums as integers
+func MarshalTraceRequest(req *coltracepb.ExportTraceServiceRequest) ([]byte, error) {
+	data, err := protojson.MarshalOptions{UseEnumNumbers: true}.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	return rewriteIDs(data, func(id []byte) string { return hex.EncodeToString(id) }, base64.StdEncoding.DecodeString)
+}
+
+// UnmarshalTraceRequest decodes an OTLP/JSON request, ignoring unknown fields
+// as the specification requires
+func UnmarshalTraceRequest(data []byte) (*coltracepb.ExportTraceServiceRequest, error) {
+	data, err := rewriteIDs(data, base64.StdEncoding.EncodeToString, hex.DecodeString)
+	if err != nil {
+		return nil, err
+	}
+	req := &coltracepb.ExportTraceServiceRequest{}
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(data, req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// rewriteIDs re-encodes the ID fields of a JSON document with decode and
+// encode
+func rewriteIDs(data []byte, encode func([]byte) string, decode func(string) ([]byte, error)) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	var walk func(v interface{}) error
+	walk = func(v interface{}) error {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			for key, value := range v {
+				if s, ok := value.(string); ok && idFields[key] {
+					id, err := decode(s)
+					if err != nil {
+						return fmt.Errorf("invalid %s %q: %w", key, s, err)
+					}
+					v[key] = encode(id)
+					continue
+				}
+				if err := walk(value); err != nil {
+					return err
+				}
+			}
+		case []interface{}:
+			for _, value := range v {
+				if err := walk(value); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := walk(doc); err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
+
+// Replay sends every request of an OTLP/JSON file to client and returns the
+// number of requests and spans sent. The client must be started.
+func Replay(ctx context.Context, client otlptrace.Client, r io.Reader) (requests, spans int, err error) {
+	reader := bufio.NewReader(r)
+	for lineNo := 1; ; lineNo++ {
+		line, readErr := reader.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			return requests, spans, readErr
+		}
+
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			req, err := UnmarshalTraceRequest(line)
+			if err != nil {
+				return requests, spans, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			if err := client.UploadTraces(ctx, req.ResourceSpans); err != nil {
+				return requests, spans, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			requests++
+			spans += countSpans(req.ResourceSpans)
+		}
+
+		if readErr == io.EOF {
+			return requests, spans, nil
+		}
+	}
+}
+
+func countSpans(resourceSpans []*tracepb.ResourceSpans) int {
+	n := 0
+	for _, rs := range resourceSpans {
+		for _, ss := range rs.ScopeSpans {
+			n += len(ss.Spans)
+		}
+	}
+	return n
+}
+
+// resourceSpans groups spans by resource and instrumentation scope
+func resourceSpans(spans []sdktrace.ReadOnlySpan) []*tracepb.ResourceSpans {
+	type scopeKey struct {
+		resource                 attribute.Distinct
+		name, version, schemaURL string
+		attributes               attribute.Distinct
+	}
+
+	var result []*tracepb.ResourceSpans
+	resources := map[attribute.Distinct]*tracepb.ResourceSpans{}
+	scopes := map[scopeKey]*tracepb.ScopeSpans{}
+	for _, span := range spans {
+		res := span.Resource()
+		resKey := res.Equivalent()
+		rs, ok := resources[resKey]
+		if !ok {
+			rs = &tracepb.ResourceSpans{Resource: resourceProto(res), SchemaUrl: res.SchemaURL()}
+			resources[resKey] = rs
+			result = append(result, rs)
+		}
+
+		scope := span.InstrumentationScope()
+		key := scopeKey{resKey, scope.Name, scope.Version, scope.SchemaURL, scope.Attributes.Equivalent()}
+		ss, ok := scopes[key]
+		if !ok {
+			ss = &tracepb.ScopeSpans{
+				Scope: &commonpb.InstrumentationScope{
+					Name:       scope.Name,
+					Version:    scope.Version,
+					Attributes: attributesProto(scope.Attributes.ToSlice()),
+				},
+				SchemaUrl: scope.SchemaURL,
+			}
+			scopes[key] = ss
+			rs.ScopeSpans = append(rs.ScopeSpans, ss)
+		}
+		ss.Spans = append(ss.Spans, spanProto(span))
+	}
+	return result
+}
+
+func resourceProto(res *resource.Resource) *resourcepb.Resource {
+	if res == nil {
+		return &resourcepb.Resource{}
+	}
+	return &resourcepb.Resource{Attributes: attributesProto(res.Attributes())}
+}
+
+func spanProto(span sdktrace.ReadOnlySpan) *tracepb.Span {
+	sc := span.SpanContext()
+	traceID, spanID := sc.TraceID(), sc.SpanID()
+	s := &tracepb.Span{
+		TraceId:                traceID[:],
+		SpanId:                 spanID[:],
+		TraceState:             sc.TraceState().String(),
+		Flags:                  spanFlags(sc.TraceFlags(), span.Parent().IsRemote()),
+		Name:                   span.Name(),
+		Kind:                   tracepb.Span_SpanKind(span.SpanKind()),
+		StartTimeUnixNano:      unixNano(span.StartTime()),
+		EndTimeUnixNano:        unixNano(span.EndTime()),
+		Attributes:             attributesProto(span.Attributes()),
+		DroppedAttributesCount: uint32(span.DroppedAttributes()),
+		DroppedEventsCount:     uint32(span.DroppedEvents()),
+		DroppedLinksCount:      uint32(span.DroppedLinks()),
+		Status:                 statusProto(span.Status()),
+	}
+	if parent := span.Parent(); parent.SpanID().IsValid() {
+		parentID := parent.SpanID()
+		s.ParentSpanId = parentID[:]
+	}
+
+	for _, event := range span.Events() {
+		s.Events = append(s.Events, &tracepb.Span_Event{
+			TimeUnixNano:           unixNano(event.Time),
+			Name:                   event.Name,
+			Attributes:             attributesProto(event.Attributes),
+			DroppedAttributesCount: uint32(event.DroppedAttributeCount),
+		})
+	}
+	for _, link := range span.Links() {
+		linkTraceID, linkSpanID := link.SpanContext.TraceID(), link.SpanContext.SpanID()
+		s.Links = append(s.Links, &tracepb.Span_Link{
+			TraceId:                linkTraceID[:],
+			SpanId:                 linkSpanID[:],
+			TraceState:             link.SpanContext.TraceState().String(),
+			Attributes:             attributesProto(link.Attributes),
+			DroppedAttributesCount: uint32(link.DroppedAttributeCount),
+			Flags:                  spanFlags(link.SpanContext.TraceFlags(), link.SpanContext.IsRemote()),
+		})
+	}
+	return s
+}
+
+// spanFlags encodes the W3C trace flags and whether the parent or linked
+// span context is remote
+func spanFlags(flags trace.TraceFlags, remote bool) uint32 {
+	f := uint32(flags) | uint32(tracepb.SpanFlags_SPAN_FLAGS_CONTEXT_HAS_IS_REMOTE_MASK)
+	if remote {
+		f |= uint32(tracepb.SpanFlags_SPAN_FLAGS_CONTEXT_IS_REMOTE_MASK)
+	}
+	return f
+}
+
+func statusProto(status sdktrace.Status) *tracepb.Status {
+	s := &tracepb.Status{Message: status.Description}
+	switch status.Code {
+	case codes.Ok:
+		s.Code = tracepb.Status_STATUS_CODE_OK
+	case codes.Error:
+		s.Code = tracepb.Status_STATUS_CODE_ERROR
+	}
+	return s
+}
+
+func attributesProto(attrs []attribute.KeyValue) []*commonpb.KeyValue {
+	var result []*commonpb.KeyValue
+	for _, attr := range attrs {
+		result = append(result, &commonpb.KeyValue{Key: string(attr.Key), Value: valueProto(attr.Value)})
+	}
+	return result
+}
+
+func valueProto(v attribute.Value) *commonpb.AnyValue {
+	switch v.Type() {
+	case attribute.BOOL:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: v.AsBool()}}
+	case attribute.INT64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: v.AsInt64()}}
+	case attribute.FLOAT64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: v.AsFloat64()}}
+	case attribute.BOOLSLICE:
+		var values []*commonpb.AnyValue
+		for _, b := range v.AsBoolSlice() {
+			values = append(values, valueProto(attribute.BoolValue(b)))
+		}
+		return arrayProto(values)
+	case attribute.INT64SLICE:
+		var values []*commonpb.AnyValue
+		for _, i := range v.AsInt64Slice() {
+			values = append(values, valueProto(attribute.Int64Value(i)))
+		}
+		return arrayProto(values)
+	case attribute.FLOAT64SLICE:
+		var values []*commonpb.AnyValue
+		for _, f := range v.AsFloat64Slice() {
+			values = append(values, valueProto(attribute.Float64Value(f)))
+		}
+		return arrayProto(values)
+	case attribute.STRINGSLICE:
+		var values []*commonpb.AnyValue
+		for _, s := range v.AsStringSlice() {
+			values = append(values, valueProto(attribute.StringValue(s)))
+		}
+		return arrayProto(values)
+	default:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v.Emit()}}
+	}
+}
+
+func arrayProto(values []*commonpb.AnyValue) *commonpb.AnyValue {
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: &commonpb.ArrayValue{Values: values}}}
+}
+
+func unixNano(t time.Time) uint64 {
+	if t.IsZero() || t.UnixNano() < 0 {
+		return 0
+	}
+	return uint64(t.UnixNano())
+}
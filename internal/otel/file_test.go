@@ -0,0 +1,163 @@
+package otel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// writeTestFile exports a pipeline span with a child job span to an OTLP file
+func writeTestFile(t *testing.T) (string, trace.SpanContext) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+
+	exporter, err := CreateExporter(context.Background(), "file", path, insecure)
+	if err != nil {
+		t.Fatalf("file exporter creation failed: %v", err)
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSyncer(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", "group/project"))),
+	)
+
+	ctx, pipeline := tp.Tracer("test").Start(context.Background(), "pipeline", trace.WithSpanKind(trace.SpanKindServer))
+	_, job := tp.Tracer("test").Start(ctx, "job", trace.WithAttributes(attribute.Int("job.id", 42), attribute.StringSlice("tags", []string{"docker"})))
+	job.SetStatus(codes.Error, "job failed")
+	job.End()
+	pipeline.End()
+
+	if err := tp.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+	return path, pipeline.SpanContext()
+}
+
+func TestFileExporterWritesOTLPJSON(t *testing.T) {
+	path, pipeline := writeTestFile(t)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := bytes.Split(bytes.TrimSpace(data), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("expected one line per export, got %d", len(lines))
+	}
+
+	// The job span ends first and is written on the first line
+	var req struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []struct {
+					TraceID      string          `json:"traceId"`
+					ParentSpanID string          `json:"parentSpanId"`
+					Kind         int             `json:"kind"`
+					Attributes   json.RawMessage `json:"attributes"`
+					Status       struct {
+						Code    int    `json:"code"`
+						Message string `json:"message"`
+					} `json:"status"`
+				} `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	if err := json.Unmarshal(lines[0], &req); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	job := req.ResourceSpans[0].ScopeSpans[0].Spans[0]
+
+	if job.TraceID != pipeline.TraceID().String() {
+		t.Errorf("expected hex trace ID %s, got %s", pipeline.TraceID(), job.TraceID)
+	}
+	if job.ParentSpanID != pipeline.SpanID().String() {
+		t.Errorf("expected hex parent span ID %s, got %s", pipeline.SpanID(), job.ParentSpanID)
+	}
+	if job.Kind != 1 {
+		t.Errorf("expected internal span kind as integer 1, got %d", job.Kind)
+	}
+	if job.Status.Code != 2 || job.Status.Message != "job failed" {
+		t.Errorf("expected error status as integer 2, got %+v", job.Status)
+	}
+	if !bytes.Contains(job.Attributes, []byte(`{"key":"job.id","value":{"intValue":"42"}}`)) {
+		t.Errorf("expected job.id as string-encoded int, got %s", job.Attributes)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	path, pipeline := writeTestFile(t)
+
+	var received []*coltracepb.ExportTraceServiceRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		req := &coltracepb.ExportTraceServiceRequest{}
+		if err := proto.Unmarshal(body, req); err != nil {
+			t.Errorf("collector received invalid protobuf: %v", err)
+		}
+		received = append(received, req)
+		w.Header().Set("Content-Type", "application/x-protobuf")
+	}))
+	defer server.Close()
+
+	client, err := CreateTraceClient("http/protobuf", server.URL+"/v1/traces", insecure)
+	if err != nil {
+		t.Fatalf("client creation failed: %v", err)
+	}
+	ctx := context.Background()
+	if err := client.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Stop(ctx)
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	requests, spans, err := Replay(ctx, client, file)
+	if err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	if requests != 2 || spans != 2 || len(received) != 2 {
+		t.Fatalf("expected 2 requests with 2 spans, replayed %d/%d, received %d", requests, spans, len(received))
+	}
+
+	span := received[1].ResourceSpans[0].ScopeSpans[0].Spans[0]
+	if span.Name != "pipeline" {
+		t.Errorf("expected pipeline span, got %s", span.Name)
+	}
+	if traceID := pipeline.TraceID(); !bytes.Equal(span.TraceId, traceID[:]) {
+		t.Errorf("trace ID changed during replay: %x", span.TraceId)
+	}
+}
+
+func TestReplayInvalidLine(t *testing.T) {
+	client, err := CreateTraceClient("grpc", "localhost:4317", insecure)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	line := `{"resourceSpans":[{"scopeSpans":[{"spans":[{"traceId":"not-hex"}]}]}]}`
+	requests, _, err := Replay(context.Background(), client, bytes.NewReader([]byte("\n"+line)))
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("expected an error for the invalid trace ID on line 2, got %v", err)
+	}
+	if requests != 0 {
+		t.Errorf("expected no requests sent, got %d", requests)
+	}
+}
@@ -0,0 +1,117 @@
+package otel
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type idKeyContextKey struct{}
+
+// IDGenerator derives trace and span IDs from the GitLab entity key carried
+// in the context passed to Tracer.Start. Spans started without a key get
+// random IDs, as with the SDK default generator.
+type IDGenerator struct{}
+
+var _ sdktrace.IDGenerator = IDGenerator{}
+
+// NewIDs returns the trace and span ID for a root span
+func (IDGenerator) NewIDs(ctx context.Context) (trace.TraceID, trace.SpanID) {
+	if key, ok := idKeyFromContext(ctx); ok {
+		return traceIDFromKey(key), spanIDFromKey(key)
+	}
+	return randomTraceID(), randomSpanID()
+}
+
+// NewSpanID returns the span ID for a child span of traceID
+func (IDGenerator) NewSpanID(ctx context.Context, _ trace.TraceID) trace.SpanID {
+	if key, ok := idKeyFromContext(ctx); ok {
+		return spanIDFromKey(key)
+	}
+	return randomSpanID()
+}
+
+// WithIDKey returns a context whose next span gets IDs derived from key.
+// The returned context must only be used to start that one span.
+func WithIDKey(ctx context.Context, key string) context.Context {
+	if key == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, idKeyContextKey{}, key)
+}
+
+// PipelineIDKey returns the ID key of a pipeline span
+func PipelineIDKey(projectID, pipelineID int) string {
+	return fmt.Sprintf("gitlab/project/%d/pipeline/%d", projectID, pipelineID)
+}
+
+// StageIDKey returns the ID key of a stage span
+func StageIDKey(projectID, pipelineID int, stage string) string {
+	return fmt.Sprintf("%s/stage/%s", PipelineIDKey(projectID, pipelineID), stage)
+}
+
+// JobIDKey returns the ID key of a job or bridge span
+func JobIDKey(jobID int) string {
+	return fmt.Sprintf("gitlab/job/%d", jobID)
+}
+
+// JobChildIDKey returns the ID key of a named child span of a job span
+func JobChildIDKey(jobID int, name string) string {
+	return fmt.Sprintf("%s/%s", JobIDKey(jobID), name)
+}
+
+// PipelineTraceID returns the trace ID of a pipeline exported as a trace root
+// with deterministic IDs
+func PipelineTraceID(projectID, pipelineID int) trace.TraceID {
+	return traceIDFromKey(PipelineIDKey(projectID, pipelineID))
+}
+
+// PipelineSpanContext returns the span context of the root span of a pipeline
+// exported as a trace root with deterministic IDs
+func PipelineSpanContext(projectID, pipelineID int) trace.SpanContext {
+	key := PipelineIDKey(projectID, pipelineID)
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceIDFromKey(key),
+		SpanID:     spanIDFromKey(key),
+		TraceFlags: trace.FlagsSampled,
+	})
+}
+
+func idKeyFromContext(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(idKeyContextKey{}).(string)
+	return key, ok && key != ""
+}
+
+func traceIDFromKey(key string) trace.TraceID {
+	sum := sha256.Sum256([]byte("trace:" + key))
+	var id trace.TraceID
+	copy(id[:], sum[:])
+	return id
+}
+
+func spanIDFromKey(key string) trace.SpanID {
+	sum := sha256.Sum256([]byte("span:" + key))
+	var id trace.SpanID
+	copy(id[:], sum[:])
+	return id
+}
+
+func randomTraceID() trace.TraceID {
+	var id trace.TraceID
+	for !id.IsValid() {
+		_, _ = rand.Read(id[:])
+	}
+	return id
+}
+
+func randomSpanID() trace.SpanID {
+	var id trace.SpanID
+	for !id.IsValid() {
+		_, _ = rand.Read(id[:])
+	}
+	return id
+}
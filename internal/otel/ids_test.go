@@ -0,0 +1,56 @@
+package otel
+
+import (
+	"context"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestIDGeneratorDeterministic(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSyncer(exporter),
+		sdktrace.WithIDGenerator(IDGenerator{}),
+	)
+	defer func() { _ = tp.Shutdown(context.Background()) }()
+	tracer := tp.Tracer("test")
+
+	for i := 0; i < 2; i++ {
+		ctx, pipelineSpan := tracer.Start(WithIDKey(context.Background(), PipelineIDKey(1, 2)), "pipeline")
+		_, jobSpan := tracer.Start(WithIDKey(ctx, JobIDKey(3)), "job")
+		jobSpan.End()
+		pipelineSpan.End()
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 4 {
+		t.Fatalf("expected 4 spans, got %d", len(spans))
+	}
+
+	want := PipelineSpanContext(1, 2)
+	for _, span := range spans {
+		if span.SpanContext.TraceID() != PipelineTraceID(1, 2) {
+			t.Errorf("span %s has trace ID %s, want %s", span.Name, span.SpanContext.TraceID(), PipelineTraceID(1, 2))
+		}
+	}
+	if spans[1].SpanContext.SpanID() != want.SpanID() || spans[3].SpanContext.SpanID() != want.SpanID() {
+		t.Error("pipeline span IDs should be derived from the pipeline key")
+	}
+	if spans[0].SpanContext.SpanID() != spans[2].SpanContext.SpanID() {
+		t.Error("job span IDs should be identical across exports")
+	}
+}
+
+func TestIDGeneratorRandomWithoutKey(t *testing.T) {
+	gen := IDGenerator{}
+	traceA, spanA := gen.NewIDs(context.Background())
+	traceB, spanB := gen.NewIDs(context.Background())
+	if !traceA.IsValid() || !spanA.IsValid() {
+		t.Error("random IDs should be valid")
+	}
+	if traceA == traceB || spanA == spanB {
+		t.Error("IDs without a key should be random")
+	}
+}
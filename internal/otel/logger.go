@@ -0,0 +1,61 @@
+package otel
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/log/global"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/config"
+)
+
+var (
+	errorRegex = regexp.MustCompile(`(?i)\b(error|fatal|panic|failed|failure|exception)\b`)
+	warnRegex  = regexp.MustCompile(`(?i)\b(warn|warning|deprecated)\b`)
+	debugRegex = regexp.MustCompile(`(?i)\b(debug|trace)\b`)
+)
+
+// InitLogger initializes OpenTelemetry logger provider with configuration
+func InitLogger(ctx context.Context, cfg *config.Config) (*sdklog.LoggerProvider, error) {
+	endpoint := cfg.SignalEndpoint("logs")
+	fmt.Printf("Connecting to OTLP logs endpoint: %s (protocol: %s)\n", endpoint, cfg.Protocol)
+
+	conn, err := NewConnection(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	exporter, err := CreateLogExporter(ctx, cfg.Protocol, endpoint, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := newResource(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	lp := sdklog.NewLoggerProvider(
+		sdklog.WithProcessor(sdklog.NewBatchProcessor(exporter)),
+		sdklog.WithResource(res),
+	)
+	global.SetLoggerProvider(lp)
+
+	return lp, nil
+}
+
+// InferSeverity guesses the severity of a job log line from its content
+func InferSeverity(line string) log.Severity {
+	switch {
+	case errorRegex.MatchString(line):
+		return log.SeverityError
+	case warnRegex.MatchString(line):
+		return log.SeverityWarn
+	case debugRegex.MatchString(line):
+		return log.SeverityDebug
+	default:
+		return log.SeverityInfo
+	}
+}
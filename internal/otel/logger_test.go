@@ -0,0 +1,26 @@
+package otel
+
+import (
+	"testing"
+
+	"go.opentelemetry.io/otel/log"
+)
+
+func TestInferSeverity(t *testing.T) {
+	tests := []struct {
+		line string
+		want log.Severity
+	}{
+		{"ERROR: Job failed: exit code 1", log.SeverityError},
+		{"npm WARN deprecated package@1.0.0", log.SeverityWarn},
+		{"DEBUG connecting to cache", log.SeverityDebug},
+		{"$ make build", log.SeverityInfo},
+		{"errors.go compiled", log.SeverityInfo},
+	}
+
+	for _, tt := range tests {
+		if got := InferSeverity(tt.line); got != tt.want {
+			t.Errorf("InferSeverity(%q) = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+}
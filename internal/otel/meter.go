@@ -0,0 +1,43 @@
+package otel
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/config"
+)
+
+// InitMeter initializes OpenTelemetry meter provider with configuration.
+// Metrics are exported periodically, every 60s unless
+// OTEL_METRIC_EXPORT_INTERVAL says otherwise, which matters in the
+// long-running serve and poll modes, and once more when the provider is shut
+// down.
+func InitMeter(ctx context.Context, cfg *config.Config) (*sdkmetric.MeterProvider, error) {
+	endpoint := cfg.SignalEndpoint("metrics")
+	fmt.Printf("Connecting to OTLP metrics endpoint: %s (protocol: %s)\n", endpoint, cfg.Protocol)
+
+	conn, err := NewConnection(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	exporter, err := CreateMetricExporter(ctx, cfg.Protocol, endpoint, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := newResource(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	mp := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter)),
+		sdkmetric.WithResource(res),
+	)
+	otel.SetMeterProvider(mp)
+
+	return mp, nil
+}
@@ -3,32 +3,37 @@ package otel
 import (
 	"context"
 	"fmt"
-	"os"
+	"log"
 	"strconv"
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/propagation"
+	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/config"
 	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/gitlab"
 )
 
 // ExtractParentContext extracts trace context from parent pipeline
-func ExtractParentContext(ctx context.Context, gitClient *gitlab.Client, pipeline *gitlab.PipelineData) context.Context {
+func ExtractParentContext(ctx context.Context, cfg *config.Config, source gitlab.PipelineSource, pipeline *gitlab.PipelineData) context.Context {
 	// Check if this pipeline was triggered by another pipeline
-	if os.Getenv("CI_PIPELINE_SOURCE") != "pipeline" && os.Getenv("CI_PIPELINE_SOURCE") != "trigger" {
+	if cfg.PipelineSource != "pipeline" && cfg.PipelineSource != "trigger" {
 		return ctx
 	}
 
 	// Look for parent pipeline trace context in variables
-	if traceParent := os.Getenv("TRACEPARENT"); traceParent != "" {
-		carrier := propagation.MapCarrier{"traceparent": traceParent}
+	if cfg.TraceParent != "" {
+		carrier := propagation.MapCarrier{"traceparent": cfg.TraceParent}
 		return otel.GetTextMapPropagator().Extract(ctx, carrier)
 	}
 
 	// Try to extract from pipeline variables if available
-	projectID := os.Getenv("CI_PROJECT_ID")
-	pipelineID, _ := strconv.Atoi(os.Getenv("CI_PIPELINE_ID"))
+	projectID := cfg.ProjectID
+	pipelineID, err := strconv.Atoi(cfg.PipelineID)
+	if err != nil {
+		log.Printf("invalid pipeline ID %q, not looking for a TRACEPARENT variable: %v", cfg.PipelineID, err)
+		return ctx
+	}
 
-	if variables, _, err := gitClient.GetClient().Pipelines.GetPipelineVariables(projectID, pipelineID, nil); err == nil {
+	if variables, err := source.FetchPipelineVariables(ctx, projectID, pipelineID); err == nil {
 		for _, v := range variables {
 			if v.Key == "TRACEPARENT" {
 				carrier := propagation.MapCarrier{"traceparent": v.Value}
@@ -3,9 +3,10 @@ package otel
 import (
 	"context"
 	"fmt"
-	"os"
+	"sort"
 
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
@@ -15,34 +16,83 @@ import (
 
 // InitTracer initializes OpenTelemetry tracer with configuration
 func InitTracer(ctx context.Context, cfg *config.Config) (*sdktrace.TracerProvider, error) {
-	endpoint := cfg.GetEndpoint()
-	fmt.Printf("Connecting to OTLP endpoint: %s (protocol: %s)\n", endpoint, cfg.Protocol)
-
-	exporter, err := CreateExporter(ctx, cfg.Protocol, endpoint)
+	exporter, err := createTraceExporter(ctx, cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	serviceName := fmt.Sprintf("%s/%s",
-		os.Getenv("CI_PROJECT_NAMESPACE"),
-		os.Getenv("CI_PROJECT_NAME"))
-
-	res, err := resource.New(ctx,
-		resource.WithAttributes(
-			semconv.ServiceNameKey.String(serviceName),
-			semconv.ServiceVersionKey.String(os.Getenv("CI_COMMIT_SHA")),
-		),
-	)
+	res, err := newResource(ctx, cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	tp := sdktrace.NewTracerProvider(
+	opts := []sdktrace.TracerProviderOption{
 		sdktrace.WithBatcher(exporter),
 		sdktrace.WithResource(res),
-	)
+	}
+	if cfg.DeterministicIDs {
+		opts = append(opts, sdktrace.WithIDGenerator(IDGenerator{}))
+	}
+
+	tp := sdktrace.NewTracerProvider(opts...)
 	otel.SetTracerProvider(tp)
 	otel.SetTextMapPropagator(propagation.TraceContext{})
 
 	return tp, nil
 }
+
+// createTraceExporter creates the exporter of the configured protocol, or
+// one rendering the trace tree in a dry run
+func createTraceExporter(ctx context.Context, cfg *config.Config) (sdktrace.SpanExporter, error) {
+	if cfg.DryRun {
+		fmt.Println("Dry run: rendering the trace tree instead of exporting it")
+		return NewTreeExporter(cfg.DryRunOutput, cfg.DryRunFormat), nil
+	}
+
+	endpoint := cfg.SignalEndpoint("traces")
+	fmt.Printf("Connecting to OTLP endpoint: %s (protocol: %s)\n", endpoint, cfg.Protocol)
+
+	conn, err := NewConnection(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return CreateExporter(ctx, cfg.Protocol, endpoint, conn)
+}
+
+// defaultServiceName names the service when no project is configured, as in
+// the serve, poll and backfill modes, which export pipelines of any project
+const defaultServiceName = "gitlab-otel-exporter"
+
+// newResource creates the resource shared by all telemetry signals. The
+// service is named after the project, or defaultServiceName outside of a
+// pipeline, unless OTEL_SERVICE_NAME or OTEL_RESOURCE_ATTRIBUTES say
+// otherwise.
+func newResource(ctx context.Context, cfg *config.Config) (*resource.Resource, error) {
+	values, err := cfg.ResourceAttributeMap()
+	if err != nil {
+		return nil, fmt.Errorf("invalid resource attributes: %w", err)
+	}
+
+	serviceName := defaultServiceName
+	if cfg.ProjectNamespace != "" && cfg.ProjectName != "" {
+		serviceName = fmt.Sprintf("%s/%s", cfg.ProjectNamespace, cfg.ProjectName)
+	}
+	attrs := []attribute.KeyValue{
+		semconv.ServiceNameKey.String(serviceName),
+		semconv.ServiceVersionKey.String(cfg.CommitSHA),
+	}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		attrs = append(attrs, attribute.String(key, values[key]))
+	}
+	if cfg.ServiceName != "" {
+		attrs = append(attrs, semconv.ServiceNameKey.String(cfg.ServiceName))
+	}
+
+	// Later attributes with the same key take precedence
+	return resource.New(ctx, resource.WithAttributes(attrs...))
+}
@@ -0,0 +1,65 @@
+package otel
+
+import (
+	"context"
+	"testing"
+
+	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/config"
+)
+
+func TestNewResource(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         config.Config
+		wantService string
+		wantVersion string
+	}{
+		{
+			name:        "project",
+			cfg:         config.Config{ProjectNamespace: "group", ProjectName: "app", CommitSHA: "abc123"},
+			wantService: "group/app",
+			wantVersion: "abc123",
+		},
+		{
+			name: "resource attributes",
+			cfg: config.Config{
+				ProjectNamespace:   "group",
+				ProjectName:        "app",
+				ResourceAttributes: "service.name=ci,service.version=1.2.3,deployment.environment=prod",
+			},
+			wantService: "ci",
+			wantVersion: "1.2.3",
+		},
+		{
+			name:        "no project",
+			cfg:         config.Config{},
+			wantService: "gitlab-otel-exporter",
+		},
+		{
+			name: "service name",
+			cfg: config.Config{
+				ServiceName:        "gitlab-ci",
+				ResourceAttributes: "service.name=ci",
+			},
+			wantService: "gitlab-ci",
+		},
+	}
+
+	for _, tt := range tests {
+		res, err := newResource(context.Background(), &tt.cfg)
+		if err != nil {
+			t.Fatalf("%s: newResource failed: %v", tt.name, err)
+		}
+
+		found := map[string]string{}
+		for _, attr := range res.Attributes() {
+			found[string(attr.Key)] = attr.Value.AsString()
+		}
+		if found["service.name"] != tt.wantService {
+			t.Errorf("%s: service.name = %q, want %q", tt.name, found["service.name"], tt.wantService)
+		}
+		if found["service.version"] != tt.wantVersion {
+			t.Errorf("%s: service.version = %q, want %q", tt.name, found["service.version"], tt.wantVersion)
+		}
+	}
+}
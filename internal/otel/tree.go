@@ -0,0 +1,198 @@
+package otel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TreeExporter collects spans instead of exporting them and renders them as
+// a tree on shutdown, to review the shape of a trace in a dry run
+type TreeExporter struct {
+	mu     sync.Mutex
+	spans  []sdktrace.ReadOnlySpan
+	path   string
+	format string
+}
+
+// TreeNode is a span in the rendered tree
+type TreeNode struct {
+	Name              string      `json:"name"`
+	Kind              string      `json:"kind"`
+	Status            string      `json:"status"`
+	StatusDescription string      `json:"status_description,omitempty"`
+	Start             time.Time   `json:"start"`
+	Duration          string      `json:"duration"`
+	Attributes        int         `json:"attributes"`
+	Events            int         `json:"events,omitempty"`
+	Links             int         `json:"links,omitempty"`
+	Children          []*TreeNode `json:"children,omitempty"`
+}
+
+// NewTreeExporter creates an exporter rendering the tree as text or json to
+// the file at path, or to standard output when path is empty
+func NewTreeExporter(path, format string) *TreeExporter {
+	return &TreeExporter{path: path, format: format}
+}
+
+// ExportSpans collects spans
+func (e *TreeExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+// Shutdown renders the collected spans
+func (e *TreeExporter) Shutdown(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	roots := BuildTree(e.spans)
+	e.spans = nil
+
+	var buf bytes.Buffer
+	if e.format == "json" {
+		if roots == nil {
+			roots = []*TreeNode{}
+		}
+		enc := json.NewEncoder(&buf)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(roots); err != nil {
+			return err
+		}
+	} else if err := RenderTree(&buf, roots); err != nil {
+		return err
+	}
+
+	if e.path == "" {
+		_, err := os.Stdout.Write(buf.Bytes())
+		return err
+	}
+	if err := os.WriteFile(e.path, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to write trace tree: %w", err)
+	}
+	fmt.Printf("Trace tree written to %s\n", e.path)
+	return nil
+}
+
+// BuildTree arranges spans by parent, children ordered by start time. Spans
+// whose parent is not among spans, such as a pipeline continuing a remote
+// trace, are roots.
+func BuildTree(spans []sdktrace.ReadOnlySpan) []*TreeNode {
+	type spanKey struct {
+		traceID trace.TraceID
+		spanID  trace.SpanID
+	}
+
+	nodes := make(map[spanKey]*TreeNode, len(spans))
+	for _, span := range spans {
+		sc := span.SpanContext()
+		nodes[spanKey{sc.TraceID(), sc.SpanID()}] = treeNode(span)
+	}
+
+	var roots []*TreeNode
+	for _, span := range spans {
+		sc := span.SpanContext()
+		node := nodes[spanKey{sc.TraceID(), sc.SpanID()}]
+		if parent, ok := nodes[spanKey{sc.TraceID(), span.Parent().SpanID()}]; ok && span.Parent().IsValid() {
+			parent.Children = append(parent.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+
+	for _, node := range nodes {
+		sortNodes(node.Children)
+	}
+	sortNodes(roots)
+	return roots
+}
+
+func treeNode(span sdktrace.ReadOnlySpan) *TreeNode {
+	node := &TreeNode{
+		Name:       span.Name(),
+		Kind:       span.SpanKind().String(),
+		Status:     "unset",
+		Start:      span.StartTime().UTC(),
+		Duration:   span.EndTime().Sub(span.StartTime()).String(),
+		Attributes: len(span.Attributes()),
+		Events:     len(span.Events()),
+		Links:      len(span.Links()),
+	}
+	switch span.Status().Code {
+	case codes.Ok:
+		node.Status = "ok"
+	case codes.Error:
+		node.Status = "error"
+		node.StatusDescription = span.Status().Description
+	}
+	return node
+}
+
+func sortNodes(nodes []*TreeNode) {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		if !nodes[i].Start.Equal(nodes[j].Start) {
+			return nodes[i].Start.Before(nodes[j].Start)
+		}
+		return nodes[i].Name < nodes[j].Name
+	})
+}
+
+// RenderTree writes one line per span, indented below its parent
+func RenderTree(w io.Writer, roots []*TreeNode) error {
+	var b strings.Builder
+	for _, root := range roots {
+		b.WriteString(root.summary() + "\n")
+		renderChildren(&b, root.Children, "")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func renderChildren(b *strings.Builder, children []*TreeNode, prefix string) {
+	for i, child := range children {
+		branch, indent := "├── ", "│   "
+		if i == len(children)-1 {
+			branch, indent = "└── ", "    "
+		}
+		b.WriteString(prefix + branch + child.summary() + "\n")
+		renderChildren(b, child.Children, prefix+indent)
+	}
+}
+
+// summary describes a span on one line, such as
+// "build [ok] 1m30s, 12 attributes, 1 event"
+func (n *TreeNode) summary() string {
+	status := n.Status
+	if n.StatusDescription != "" {
+		status += ": " + n.StatusDescription
+	}
+
+	s := fmt.Sprintf("%s [%s] %s, %s", n.Name, status, n.Duration, plural(n.Attributes, "attribute"))
+	if n.Events > 0 {
+		s += ", " + plural(n.Events, "event")
+	}
+	if n.Links > 0 {
+		s += ", " + plural(n.Links, "link")
+	}
+	return s
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s", noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
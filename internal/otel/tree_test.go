@@ -0,0 +1,98 @@
+package otel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordTestTrace records a pipeline with a stage of two jobs, started out of
+// order, into exporter
+func recordTestTrace(t *testing.T, exporter sdktrace.SpanExporter) {
+	t.Helper()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	tracer := tp.Tracer("test")
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	ctx, pipeline := tracer.Start(context.Background(), "group/app #1", trace.WithTimestamp(start),
+		trace.WithAttributes(attribute.String("cicd.pipeline.name", "main")))
+	ctx, stage := tracer.Start(ctx, "Stage: test", trace.WithTimestamp(start))
+
+	_, lint := tracer.Start(ctx, "lint", trace.WithTimestamp(start.Add(time.Minute)))
+	lint.SetStatus(codes.Error, "job failed")
+	lint.End(trace.WithTimestamp(start.Add(2 * time.Minute)))
+
+	_, unit := tracer.Start(ctx, "unit", trace.WithTimestamp(start.Add(10*time.Second)),
+		trace.WithAttributes(attribute.Int("job.id", 1), attribute.Int("job.retries", 0)))
+	unit.AddEvent("pending", trace.WithTimestamp(start.Add(10*time.Second)))
+	unit.SetStatus(codes.Ok, "")
+	unit.End(trace.WithTimestamp(start.Add(90 * time.Second)))
+
+	stage.End(trace.WithTimestamp(start.Add(2 * time.Minute)))
+	pipeline.SetStatus(codes.Ok, "")
+	pipeline.End(trace.WithTimestamp(start.Add(3 * time.Minute)))
+
+	if err := tp.ForceFlush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRenderTree(t *testing.T) {
+	exporter := NewTreeExporter("", "text")
+	recordTestTrace(t, exporter)
+
+	var out bytes.Buffer
+	if err := RenderTree(&out, BuildTree(exporter.spans)); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `group/app #1 [ok] 3m0s, 1 attribute
+└── Stage: test [unset] 2m0s, 0 attributes
+    ├── unit [ok] 1m20s, 2 attributes, 1 event
+    └── lint [error: job failed] 1m0s, 0 attributes
+`
+	if out.String() != want {
+		t.Errorf("unexpected tree:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestTreeExporterWritesJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tree.json")
+	exporter := NewTreeExporter(path, "json")
+	recordTestTrace(t, exporter)
+	if err := exporter.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var roots []*TreeNode
+	if err := json.Unmarshal(data, &roots); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, data)
+	}
+
+	if len(roots) != 1 || len(roots[0].Children) != 1 {
+		t.Fatalf("expected a pipeline with one stage, got %s", data)
+	}
+	jobs := roots[0].Children[0].Children
+	if len(jobs) != 2 || jobs[0].Name != "unit" || jobs[1].Name != "lint" {
+		t.Fatalf("expected jobs ordered by start time, got %s", data)
+	}
+	if jobs[1].Status != "error" || jobs[1].StatusDescription != "job failed" || jobs[1].Duration != "1m0s" {
+		t.Errorf("unexpected lint job node: %+v", jobs[1])
+	}
+	if roots[0].Kind != "internal" || roots[0].Attributes != 1 {
+		t.Errorf("unexpected pipeline node: %+v", roots[0])
+	}
+}
@@ -0,0 +1,125 @@
+package poller
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/checkpoint"
+	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/config"
+	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/gitlab"
+)
+
+const (
+	// cursorOverlap re-reads a short window before the cursor on every poll,
+	// as pipeline updates may be committed out of order
+	cursorOverlap = time.Minute
+
+	// exportedRetention is how long exported pipelines are remembered in the
+	// checkpoint
+	exportedRetention = 7 * 24 * time.Hour
+)
+
+// ExportFunc exports the trace of one pipeline
+type ExportFunc func(ctx context.Context, projectID string, pipelineID int) error
+
+// Poller periodically lists recently updated pipelines of the configured
+// projects and groups, and exports each pipeline once it is finished
+type Poller struct {
+	config     *config.Config
+	lister     gitlab.PipelineLister
+	checkpoint *checkpoint.Checkpoint
+	export     ExportFunc
+}
+
+// New creates a poller
+func New(cfg *config.Config, lister gitlab.PipelineLister, cp *checkpoint.Checkpoint, export ExportFunc) *Poller {
+	return &Poller{
+		config:     cfg,
+		lister:     lister,
+		checkpoint: cp,
+		export:     export,
+	}
+}
+
+// Run polls until ctx is canceled
+func (p *Poller) Run(ctx context.Context) error {
+	ticker := time.NewTicker(p.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := p.PollOnce(ctx); err != nil {
+			log.Printf("poll failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// PollOnce exports the finished pipelines updated since the last poll of each
+// project and saves the checkpoint
+func (p *Poller) PollOnce(ctx context.Context) error {
+	// Group projects are resolved on every poll to pick up new projects
+	projects, err := p.lister.ResolveProjects(ctx, p.config.PollProjects, p.config.PollGroups)
+	if err != nil {
+		return err
+	}
+
+	for _, project := range projects {
+		if ctx.Err() != nil {
+			break
+		}
+		p.pollProject(ctx, project)
+	}
+
+	p.checkpoint.Prune(exportedRetention)
+	return p.checkpoint.Save()
+}
+
+func (p *Poller) pollProject(ctx context.Context, project string) {
+	cursor, ok := p.checkpoint.Cursor(project)
+	if !ok {
+		cursor = time.Now().Add(-p.config.PollLookback)
+	}
+	since := cursor.Add(-cursorOverlap)
+
+	pipelines, err := p.lister.ListPipelines(ctx, project, gitlab.PipelineFilter{UpdatedAfter: &since})
+	if err != nil {
+		log.Printf("failed to list pipelines of project %s: %v", project, err)
+		return
+	}
+
+	failed := false
+	for _, pipeline := range pipelines {
+		// Unfinished pipelines are picked up again once their update time
+		// moves past the cursor
+		if !gitlab.IsTerminalStatus(pipeline.Status) || pipeline.UpdatedAt == nil {
+			continue
+		}
+
+		key := checkpoint.PipelineKey(project, pipeline.ID, *pipeline.UpdatedAt)
+		if !p.checkpoint.IsExported(key) {
+			if err := p.export(ctx, project, pipeline.ID); err != nil {
+				log.Printf("failed to export pipeline %d of project %s: %v", pipeline.ID, project, err)
+				failed = true
+				continue
+			}
+			p.checkpoint.MarkExported(key)
+			fmt.Printf("Exported pipeline %d of project %s\n", pipeline.ID, project)
+		}
+
+		if pipeline.UpdatedAt.After(cursor) {
+			cursor = *pipeline.UpdatedAt
+		}
+	}
+
+	// Keep the cursor in place after a failure so the pipeline is retried
+	if !failed {
+		p.checkpoint.SetCursor(project, cursor)
+	}
+}
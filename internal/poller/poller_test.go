@@ -0,0 +1,82 @@
+package poller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/checkpoint"
+	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/config"
+	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/gitlab"
+)
+
+func newTestAPI(t *testing.T) *httptest.Server {
+	t.Helper()
+	routes := map[string]interface{}{
+		"/api/v4/groups/platform/projects": []map[string]interface{}{{"id": 11}},
+		"/api/v4/projects/10/pipelines": []map[string]interface{}{
+			{"id": 100, "status": "success", "updated_at": "2026-01-01T10:00:00Z"},
+			{"id": 101, "status": "running", "updated_at": "2026-01-01T10:05:00Z"},
+		},
+		"/api/v4/projects/11/pipelines": []map[string]interface{}{
+			{"id": 110, "status": "failed", "updated_at": "2026-01-01T09:00:00Z"},
+		},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := routes[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestPollOnceExportsFinishedPipelinesOnce(t *testing.T) {
+	api := newTestAPI(t)
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	cfg := &config.Config{
+		ServerURL:    api.URL,
+		PollProjects: []string{"10"},
+		PollGroups:   []string{"platform"},
+		PollLookback: 24 * time.Hour * 365 * 10,
+	}
+	gitClient, err := gitlab.NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	var exported []int
+	export := func(_ context.Context, _ string, pipelineID int) error {
+		exported = append(exported, pipelineID)
+		return nil
+	}
+
+	// Two runs with a checkpoint reloaded from disk, as across a restart
+	for i := 0; i < 2; i++ {
+		cp, err := checkpoint.Load(path)
+		if err != nil {
+			t.Fatalf("checkpoint.Load failed: %v", err)
+		}
+		if err := New(cfg, gitClient, cp, export).PollOnce(context.Background()); err != nil {
+			t.Fatalf("PollOnce failed: %v", err)
+		}
+	}
+
+	if len(exported) != 2 || exported[0] != 100 || exported[1] != 110 {
+		t.Errorf("expected finished pipelines 100 and 110 exported once, got %v", exported)
+	}
+
+	cp, _ := checkpoint.Load(path)
+	if cursor, ok := cp.Cursor("10"); !ok || cursor.Format("15:04") != "10:00" {
+		t.Errorf("cursor should stop at the last finished pipeline, got %v", cursor)
+	}
+}
@@ -2,12 +2,18 @@ package spans
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
-	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
 
 	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/attribute"
+	otellog "go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/log/global"
 	"go.opentelemetry.io/otel/trace"
 	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/config"
 	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/gitlab"
@@ -18,37 +24,82 @@ import (
 
 // Exporter handles span creation and export
 type Exporter struct {
-	config    *config.Config
-	gitClient *gitlab.Client
-	tracer    trace.Tracer
+	config  *config.Config
+	source  gitlab.PipelineSource
+	tracer  trace.Tracer
+	logger  otellog.Logger
+	metrics *pipelineMetrics
+
+	// pipelineSpans holds the span context of every pipeline span created
+	// so far, keyed by pipeline ID, so bridges can link to downstream pipelines
+	pipelineSpans map[int]trace.SpanContext
+
+	// jobSpans holds the span context of every job span created so far,
+	// keyed by job ID, so a retried job can link to its previous attempt and
+	// a job to the jobs it needs
+	jobSpans map[int]trace.SpanContext
+
+	// visited holds the IDs of pipelines already exported in this run
+	visited map[int]bool
+
+	// rootPipelines is set while exporting by ID, where every pipeline not
+	// exported as a downstream of another is the root of its own trace
+	rootPipelines bool
 }
 
-// NewExporter creates a new span exporter
-func NewExporter(cfg *config.Config, gitClient *gitlab.Client) *Exporter {
+// NewExporter creates a new span exporter reading pipelines from source,
+// usually a *gitlab.Client
+func NewExporter(cfg *config.Config, source gitlab.PipelineSource) *Exporter {
+	var metrics *pipelineMetrics
+	if cfg.ExportMetrics {
+		var err error
+		if metrics, err = newPipelineMetrics(otel.Meter("gitlab-ci-collector")); err != nil {
+			log.Printf("failed to create metric instruments, continuing without metrics: %v", err)
+		}
+	}
+
 	return &Exporter{
-		config:    cfg,
-		gitClient: gitClient,
-		tracer:    otel.Tracer("gitlab-ci-collector"),
+		config:        cfg,
+		source:        source,
+		tracer:        otel.Tracer("gitlab-ci-collector"),
+		logger:        global.GetLoggerProvider().Logger("gitlab-ci-collector"),
+		metrics:       metrics,
+		pipelineSpans: make(map[int]trace.SpanContext),
+		jobSpans:      make(map[int]trace.SpanContext),
 	}
 }
 
 // ExportPipeline exports traces for the entire pipeline
 func (e *Exporter) ExportPipeline(ctx context.Context) error {
+	pipelineID, err := strconv.Atoi(e.config.PipelineID)
+	if err != nil {
+		return fmt.Errorf("invalid pipeline ID %q: %w", e.config.PipelineID, err)
+	}
+
+	ctx, cancel := e.withDeadline(ctx)
+	defer cancel()
+
 	fmt.Println("Fetching pipeline data...")
-	pipeline, err := e.gitClient.FetchPipeline()
+	pipeline, err := e.source.FetchPipelineByID(ctx, e.config.ProjectID, pipelineID)
 	if err != nil {
-		return err
+		return e.deadlineErr(ctx, err)
 	}
 
 	// Check for parent pipeline context
-	ctx = otelutil.ExtractParentContext(ctx, e.gitClient, pipeline)
+	ctx = otelutil.ExtractParentContext(ctx, e.config, e.source, pipeline)
 
-	jobs, err := e.gitClient.FetchJobs()
+	jobs, err := e.source.FetchJobsByID(ctx, e.config.ProjectID, pipelineID)
 	if err != nil {
-		return err
+		return e.deadlineErr(ctx, err)
 	}
 	fmt.Printf("Found %d jobs in pipeline\n", len(jobs))
 
+	bridges, err := e.source.FetchBridgesByID(ctx, e.config.ProjectID, pipelineID)
+	if err != nil {
+		log.Printf("failed to fetch bridges, continuing without them: %v", err)
+	}
+	fmt.Printf("Found %d bridges in pipeline\n", len(bridges))
+
 	// Create pipeline span
 	ctx, pipelineSpan := e.createPipelineSpan(ctx, pipeline)
 	defer e.endPipelineSpan(pipelineSpan, pipeline)
@@ -56,10 +107,115 @@ func (e *Exporter) ExportPipeline(ctx context.Context) error {
 	// Export trace context for downstream pipelines
 	otelutil.ExportTraceContext(ctx, e.config.Debug)
 
-	// Create job spans
+	// Create job and bridge spans
 	fmt.Println("Creating job spans...")
+	e.visited = map[int]bool{pipeline.ID: true}
+	e.rootPipelines = false
+	e.exportPipelineChildren(ctx, pipeline, jobs, bridges, 0)
+
+	return e.deadlineErr(ctx, nil)
+}
+
+// ExportPipelineByID exports traces for any pipeline of any project. Unlike
+// ExportPipeline it reads nothing from the CI environment, so it can run
+// outside of a pipeline job.
+func (e *Exporter) ExportPipelineByID(ctx context.Context, projectID string, pipelineID int) error {
+	ctx, cancel := e.withDeadline(ctx)
+	defer cancel()
+
+	fmt.Printf("Fetching pipeline %d of project %s...\n", pipelineID, projectID)
+	pipeline, err := e.source.FetchPipelineByID(ctx, projectID, pipelineID)
+	if err != nil {
+		return e.deadlineErr(ctx, err)
+	}
+
+	jobs, err := e.source.FetchJobsByID(ctx, projectID, pipelineID)
+	if err != nil {
+		return e.deadlineErr(ctx, err)
+	}
+	fmt.Printf("Found %d jobs in pipeline\n", len(jobs))
+
+	bridges, err := e.source.FetchBridgesByID(ctx, projectID, pipelineID)
+	if err != nil {
+		log.Printf("failed to fetch bridges, continuing without them: %v", err)
+	}
+	fmt.Printf("Found %d bridges in pipeline\n", len(bridges))
+
+	ctx, pipelineSpan := e.createPipelineDataSpan(ctx, pipeline)
+	defer e.endPipelineSpan(pipelineSpan, pipeline)
+
+	e.visited = map[int]bool{pipeline.ID: true}
+	e.rootPipelines = true
+	e.exportPipelineChildren(ctx, pipeline, jobs, bridges, 0)
+
+	return e.deadlineErr(ctx, nil)
+}
+
+// withDeadline bounds an export by the configured export timeout, if any
+func (e *Exporter) withDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	if e.config.ExportTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, e.config.ExportTimeout)
+}
+
+// deadlineErr reports an export cut short by the export timeout. Requests
+// failing after the deadline are only logged, so the export fails here
+// rather than passing off an incomplete trace as exported.
+func (e *Exporter) deadlineErr(ctx context.Context, err error) error {
+	if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+	if err == nil {
+		err = ctx.Err()
+	}
+	return fmt.Errorf("export did not finish within export-timeout (%s), the trace is incomplete: %w", e.config.ExportTimeout, err)
+}
+
+// exportPipelineChildren creates the job and bridge spans of a pipeline at the
+// given depth below the root pipeline, grouped under stage spans when enabled.
+// Jobs that never started are recorded as the unstarted-jobs setting says.
+func (e *Exporter) exportPipelineChildren(ctx context.Context, pipeline *gitlab.PipelineData, jobs []*gitlab.JobData, bridges []*gitlab.BridgeData, depth int) {
+	if e.config.JobNeeds {
+		e.applyJobNeeds(ctx, pipeline, jobs)
+	}
+	if e.config.UnstartedJobs == "event" {
+		e.addUnstartedJobEvents(trace.SpanFromContext(ctx), pipeline, jobs, bridges)
+	}
+
+	if !e.config.StageSpans {
+		e.exportJobsAndBridges(ctx, jobs, bridges, depth)
+		return
+	}
+
+	for _, stage := range stagesInNeedOrder(groupByStage(jobs, bridges)) {
+		stageCtx, stageSpan := e.createStageSpan(ctx, pipeline, stage)
+		e.exportJobsAndBridges(stageCtx, stage.jobs, stage.bridges, depth)
+		if stageSpan != nil {
+			e.endStageSpan(stageSpan, stage)
+		}
+	}
+}
+
+// exportJobsAndBridges creates job and bridge spans under ctx, following
+// bridges into downstream pipelines when downstream export is enabled
+func (e *Exporter) exportJobsAndBridges(ctx context.Context, jobs []*gitlab.JobData, bridges []*gitlab.BridgeData, depth int) {
+	// Oldest first, so the span of each attempt exists before the retry
+	// linking to it is created, and needed jobs before the jobs needing them
+	jobs = slices.Clone(jobs)
+	sort.SliceStable(jobs, func(i, j int) bool { return jobs[i].ID < jobs[j].ID })
+	jobs = jobsInNeedOrder(jobs)
+
 	for _, job := range jobs {
-		if job.Status == "skipped" {
+		if e.metrics != nil {
+			e.metrics.recordJob(ctx, job)
+		}
+		// Jobs that never started, such as skipped jobs and manual jobs
+		// nobody played, have no duration to show
+		if job.StartedAt == nil {
+			if e.config.UnstartedJobs == "span" {
+				e.createUnstartedJobSpan(ctx, job)
+			}
 			continue
 		}
 		if err := e.createJobSpan(ctx, job); err != nil {
@@ -67,23 +223,95 @@ func (e *Exporter) ExportPipeline(ctx context.Context) error {
 		}
 	}
 
-	return nil
+	for _, bridge := range bridges {
+		// Bridges that never started, such as manual triggers nobody
+		// played, follow the same unstarted-jobs policy as jobs
+		if bridge.StartedAt == nil && e.config.UnstartedJobs == "span" {
+			e.createUnstartedBridgeSpan(ctx, bridge)
+		}
+		if err := e.createBridgeSpan(ctx, bridge, depth); err != nil {
+			log.Printf("failed to export bridge span for bridge %d: %v", bridge.ID, err)
+		}
+	}
+}
+
+// exportDownstreamPipeline exports the pipeline triggered by a bridge as a
+// child of the bridge span, guarding against cycles and excessive depth
+func (e *Exporter) exportDownstreamPipeline(ctx context.Context, bridge *gitlab.BridgeData, depth int) {
+	downstream := bridge.DownstreamPipeline
+	if downstream == nil || downstream.ID == 0 {
+		return
+	}
+	if depth > e.config.DownstreamMaxDepth {
+		log.Printf("skipping downstream pipeline %d of bridge %d: depth limit %d reached", downstream.ID, bridge.ID, e.config.DownstreamMaxDepth)
+		return
+	}
+	if e.visited[downstream.ID] {
+		log.Printf("skipping downstream pipeline %d of bridge %d: already exported", downstream.ID, bridge.ID)
+		return
+	}
+	e.visited[downstream.ID] = true
+
+	projectID := strconv.Itoa(downstream.ProjectID)
+	pipeline, err := e.source.FetchPipelineByID(ctx, projectID, downstream.ID)
+	if err != nil {
+		log.Printf("failed to fetch downstream pipeline %d: %v", downstream.ID, err)
+		return
+	}
+	jobs, err := e.source.FetchJobsByID(ctx, projectID, downstream.ID)
+	if err != nil {
+		log.Printf("failed to fetch jobs of downstream pipeline %d: %v", downstream.ID, err)
+		return
+	}
+	bridges, err := e.source.FetchBridgesByID(ctx, projectID, downstream.ID)
+	if err != nil {
+		log.Printf("failed to fetch bridges of downstream pipeline %d, continuing without them: %v", downstream.ID, err)
+	}
+
+	ctx, pipelineSpan := e.createDownstreamPipelineSpan(ctx, pipeline, bridge)
+	defer e.endPipelineSpan(pipelineSpan, pipeline)
+
+	e.exportPipelineChildren(ctx, pipeline, jobs, bridges, depth)
 }
 
 func (e *Exporter) createPipelineSpan(ctx context.Context, pipeline *gitlab.PipelineData) (context.Context, trace.Span) {
 	pipelineName := fmt.Sprintf("%s/%s #%d",
-		os.Getenv("CI_PROJECT_NAMESPACE"),
-		os.Getenv("CI_PROJECT_NAME"),
+		e.config.ProjectNamespace,
+		e.config.ProjectName,
 		pipeline.ID)
 
-	pipelineAttrs := semconv.PipelineAttributes()
+	pipelineAttrs := semconv.PipelineAttributes(e.config)
 	pipelineAttrs = append(pipelineAttrs, utils.FlattenMap("", pipeline.Raw)...)
 
 	// Add parent pipeline correlation attributes
-	if parentAttrs := semconv.ParentPipelineAttributes(e.gitClient, pipeline); len(parentAttrs) > 0 {
+	if parentAttrs := semconv.ParentPipelineAttributes(e.config, pipeline); len(parentAttrs) > 0 {
 		pipelineAttrs = append(pipelineAttrs, parentAttrs...)
 	}
 
+	return e.startPipelineSpan(ctx, pipelineName, pipeline, pipelineAttrs)
+}
+
+func (e *Exporter) createDownstreamPipelineSpan(ctx context.Context, pipeline *gitlab.PipelineData, bridge *gitlab.BridgeData) (context.Context, trace.Span) {
+	return e.createPipelineDataSpan(ctx, pipeline,
+		attribute.String("cicd.pipeline.parent.id", strconv.Itoa(bridge.Pipeline.ID)),
+		attribute.String("cicd.pipeline.parent.project.id", strconv.Itoa(bridge.Pipeline.ProjectID)),
+	)
+}
+
+// createPipelineDataSpan starts a pipeline span named and described from
+// pipeline API data alone, for pipelines other than the one the exporter
+// runs in
+func (e *Exporter) createPipelineDataSpan(ctx context.Context, pipeline *gitlab.PipelineData, extraAttrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	pipelineName := fmt.Sprintf("%s #%d", pipeline.ProjectPath(), pipeline.ID)
+
+	pipelineAttrs := semconv.PipelineDataAttributes(pipeline)
+	pipelineAttrs = append(pipelineAttrs, utils.FlattenMap("", pipeline.Raw)...)
+	pipelineAttrs = append(pipelineAttrs, extraAttrs...)
+
+	return e.startPipelineSpan(ctx, pipelineName, pipeline, pipelineAttrs)
+}
+
+func (e *Exporter) startPipelineSpan(ctx context.Context, pipelineName string, pipeline *gitlab.PipelineData, pipelineAttrs []attribute.KeyValue) (context.Context, trace.Span) {
 	var startOpts []trace.SpanStartOption
 	if pipeline.CreatedAt != nil {
 		startOpts = append(startOpts, trace.WithTimestamp(*pipeline.CreatedAt))
@@ -93,18 +321,22 @@ func (e *Exporter) createPipelineSpan(ctx context.Context, pipeline *gitlab.Pipe
 		trace.WithAttributes(pipelineAttrs...),
 	)
 
-	ctx, pipelineSpan := e.tracer.Start(ctx, pipelineName, startOpts...)
+	ctx, pipelineSpan := e.startSpan(ctx, otelutil.PipelineIDKey(pipeline.ProjectID, pipeline.ID), pipelineName, startOpts...)
 	fmt.Printf("Creating pipeline span: %s\n", pipelineName)
+	if e.pipelineSpans != nil {
+		e.pipelineSpans[pipeline.ID] = pipelineSpan.SpanContext()
+	}
+	if e.metrics != nil {
+		e.metrics.recordPipeline(ctx, pipeline)
+	}
 
 	return ctx, pipelineSpan
 }
 
 func (e *Exporter) endPipelineSpan(pipelineSpan trace.Span, pipeline *gitlab.PipelineData) {
-	if pipeline.Status == "failed" {
-		pipelineSpan.SetStatus(codes.Error, "pipeline failed")
-	} else {
-		pipelineSpan.SetStatus(codes.Ok, "")
-	}
+	status := semconv.PipelineStatus(pipeline)
+	pipelineSpan.SetAttributes(status.Attributes("cicd.pipeline.result")...)
+	pipelineSpan.SetStatus(status.Code, status.Description)
 
 	if pipeline.UpdatedAt != nil {
 		pipelineSpan.End(trace.WithTimestamp(*pipeline.UpdatedAt))
@@ -120,20 +352,134 @@ func (e *Exporter) createJobSpan(ctx context.Context, job *gitlab.JobData) error
 
 	spanName := fmt.Sprintf("Stage: %s - job_id: %d", job.Name, job.ID)
 	attrs := semconv.JobAttributes(job)
-
-	_, jobSpan := e.tracer.Start(ctx, spanName,
+	startOpts := []trace.SpanStartOption{
 		trace.WithTimestamp(*job.StartedAt),
 		trace.WithSpanKind(trace.SpanKindConsumer),
 		trace.WithAttributes(attrs...),
-	)
-	fmt.Printf("  Job: %s (%s)\n", job.Name, job.Status)
-	defer jobSpan.End(trace.WithTimestamp(*job.FinishedAt))
+	}
+	if previous, ok := e.jobSpans[job.PreviousAttemptID]; ok && job.PreviousAttemptID != 0 {
+		startOpts = append(startOpts, trace.WithLinks(trace.Link{
+			SpanContext: previous,
+			Attributes:  []attribute.KeyValue{attribute.String("cicd.pipeline.link.type", "previous_attempt")},
+		}))
+	}
+	if links := e.needLinks(job); len(links) > 0 {
+		startOpts = append(startOpts, trace.WithLinks(links...))
+	}
 
-	if job.Status == "failed" {
-		jobSpan.SetStatus(codes.Error, "job failed")
+	ctx, jobSpan := e.startSpan(ctx, otelutil.JobIDKey(job.ID), spanName, startOpts...)
+	if job.Attempt > 1 {
+		fmt.Printf("  Job: %s (%s, attempt %d)\n", job.Name, job.Status, job.Attempt)
 	} else {
-		jobSpan.SetStatus(codes.Ok, "")
+		fmt.Printf("  Job: %s (%s)\n", job.Name, job.Status)
+	}
+	if e.jobSpans != nil {
+		e.jobSpans[job.ID] = jobSpan.SpanContext()
 	}
+	defer jobSpan.End(trace.WithTimestamp(*job.FinishedAt))
+
+	e.createQueuedSpan(ctx, job)
+	if e.config.JobSections || e.config.ExportLogs {
+		e.exportJobLog(ctx, job)
+	}
+
+	status := semconv.JobStatus(job)
+	jobSpan.SetAttributes(status.Attributes("cicd.pipeline.task.run.result")...)
+	jobSpan.SetStatus(status.Code, status.Description)
 
 	return nil
 }
+
+// createQueuedSpan records the time between job creation and job start as a
+// child span of the job. The moment the job entered the runner queue, derived
+// from GitLab's queued_duration, is added as a span event.
+func (e *Exporter) createQueuedSpan(ctx context.Context, job *gitlab.JobData) {
+	if job.CreatedAt == nil || job.StartedAt == nil || !job.CreatedAt.Before(*job.StartedAt) {
+		return
+	}
+
+	_, queuedSpan := e.startSpan(ctx, otelutil.JobChildIDKey(job.ID, "queued"), "queued",
+		trace.WithTimestamp(*job.CreatedAt),
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(
+			attribute.Float64("cicd.pipeline.task.run.wait_duration", job.StartedAt.Sub(*job.CreatedAt).Seconds()),
+			attribute.Float64("cicd.pipeline.task.run.queued_duration", job.QueuedDuration),
+		),
+	)
+
+	if job.QueuedDuration > 0 {
+		pendingAt := job.StartedAt.Add(-time.Duration(job.QueuedDuration * float64(time.Second)))
+		if pendingAt.Before(*job.CreatedAt) {
+			pendingAt = *job.CreatedAt
+		}
+		queuedSpan.AddEvent("pending", trace.WithTimestamp(pendingAt))
+	}
+
+	queuedSpan.End(trace.WithTimestamp(*job.StartedAt))
+}
+
+// createBridgeSpan creates the span of a bridge at the given depth below the
+// root pipeline. The downstream pipeline, when exported, becomes a child of
+// the bridge span, which is only ended afterwards so it can link to the
+// downstream pipeline span.
+func (e *Exporter) createBridgeSpan(ctx context.Context, bridge *gitlab.BridgeData, depth int) error {
+	if bridge.StartedAt == nil || bridge.FinishedAt == nil {
+		if e.config.ExportDownstream {
+			e.exportDownstreamPipeline(ctx, bridge, depth+1)
+		}
+		return nil
+	}
+
+	spanName := fmt.Sprintf("Trigger: %s - bridge_id: %d", bridge.Name, bridge.ID)
+	ctx, bridgeSpan := e.startSpan(ctx, otelutil.JobIDKey(bridge.ID), spanName,
+		trace.WithTimestamp(*bridge.StartedAt),
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(semconv.BridgeAttributes(bridge)...),
+	)
+	fmt.Printf("  Bridge: %s (%s)\n", bridge.Name, bridge.Status)
+	defer bridgeSpan.End(trace.WithTimestamp(*bridge.FinishedAt))
+
+	if e.config.ExportDownstream {
+		e.exportDownstreamPipeline(ctx, bridge, depth+1)
+	}
+	if downstream, ok := e.downstreamSpanContext(bridge); ok {
+		bridgeSpan.AddLink(trace.Link{
+			SpanContext: downstream,
+			Attributes:  []attribute.KeyValue{attribute.String("cicd.pipeline.link.type", "downstream")},
+		})
+	}
+
+	status := semconv.BridgeStatus(bridge)
+	bridgeSpan.SetAttributes(status.Attributes("cicd.pipeline.task.run.result")...)
+	bridgeSpan.SetStatus(status.Code, status.Description)
+
+	return nil
+}
+
+// downstreamSpanContext returns the root span context of the pipeline
+// triggered by the bridge, when it is known to the exporter: the span created
+// for it in this run or, with deterministic IDs, the computed root span of a
+// pipeline exported as its own trace
+func (e *Exporter) downstreamSpanContext(bridge *gitlab.BridgeData) (trace.SpanContext, bool) {
+	if bridge.DownstreamPipeline == nil {
+		return trace.SpanContext{}, false
+	}
+	if spanCtx, ok := e.pipelineSpans[bridge.DownstreamPipeline.ID]; ok && spanCtx.IsValid() {
+		return spanCtx, true
+	}
+	if e.config.DeterministicIDs && e.rootPipelines && !e.config.ExportDownstream {
+		// Exported by ID, every pipeline is the root of a trace whose IDs
+		// can be computed from its project and pipeline ID. With recursive
+		// export the downstream pipeline belongs to this trace, and in a CI
+		// job it may join it through TRACEPARENT, so its span is unknown.
+		return otelutil.PipelineSpanContext(bridge.DownstreamPipeline.ProjectID, bridge.DownstreamPipeline.ID), true
+	}
+	return trace.SpanContext{}, false
+}
+
+// startSpan starts a span whose IDs are derived from key when deterministic
+// IDs are enabled. The key is only visible to this span, not its children.
+func (e *Exporter) startSpan(ctx context.Context, key, spanName string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	_, span := e.tracer.Start(otelutil.WithIDKey(ctx, key), spanName, opts...)
+	return trace.ContextWithSpan(ctx, span), span
+}
@@ -2,16 +2,25 @@ package spans
 
 import (
 	"context"
-	"os"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	gitlab "gitlab.com/gitlab-org/api/client-go"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/config"
 	gitlabpkg "gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/gitlab"
+	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/gitlab/gitlabtest"
+	otelutil "gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/otel"
 	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/pkg/semconv"
 )
 
@@ -64,6 +73,59 @@ func TestCreateJobSpan(t *testing.T) {
 	}
 }
 
+func TestCreateJobSpanWithQueuedSpan(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSyncer(exporter),
+	)
+	otel.SetTracerProvider(tp)
+	defer func() { _ = tp.Shutdown(context.Background()) }()
+
+	spanExporter := &Exporter{
+		config: &config.Config{},
+		tracer: otel.Tracer("test"),
+	}
+
+	finished := time.Now()
+	started := finished.Add(-5 * time.Minute)
+	created := started.Add(-2 * time.Minute)
+	job := &gitlabpkg.JobData{
+		Job: &gitlab.Job{
+			ID:             123,
+			Name:           "build",
+			Status:         "success",
+			CreatedAt:      &created,
+			StartedAt:      &started,
+			FinishedAt:     &finished,
+			QueuedDuration: 30,
+		},
+		Raw: map[string]interface{}{},
+	}
+
+	if err := spanExporter.createJobSpan(context.Background(), job); err != nil {
+		t.Errorf("createJobSpan should not error: %v", err)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected job and queued spans, got %d", len(spans))
+	}
+
+	queued := spans[0]
+	if queued.Name != "queued" {
+		t.Fatalf("expected queued span to end first, got %s", queued.Name)
+	}
+	if queued.Parent.SpanID() != spans[1].SpanContext.SpanID() {
+		t.Error("queued span should be a child of the job span")
+	}
+	if !queued.StartTime.Equal(created) || !queued.EndTime.Equal(started) {
+		t.Errorf("queued span should cover created to started, got %v - %v", queued.StartTime, queued.EndTime)
+	}
+	if len(queued.Events) != 1 || !queued.Events[0].Time.Equal(started.Add(-30*time.Second)) {
+		t.Errorf("expected pending event 30s before start, got %v", queued.Events)
+	}
+}
+
 func TestCreateJobSpanWithNilTimestamps(t *testing.T) {
 	exporter := tracetest.NewInMemoryExporter()
 	tp := sdktrace.NewTracerProvider(
@@ -102,25 +164,187 @@ func TestCreateJobSpanWithNilTimestamps(t *testing.T) {
 	}
 }
 
+func TestCreateBridgeSpanLinksDownstream(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSyncer(exporter),
+	)
+	otel.SetTracerProvider(tp)
+	defer func() { _ = tp.Shutdown(context.Background()) }()
+
+	downstream := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1},
+		SpanID:  trace.SpanID{2},
+	})
+	spanExporter := &Exporter{
+		config:        &config.Config{},
+		tracer:        otel.Tracer("test"),
+		pipelineSpans: map[int]trace.SpanContext{1000: downstream},
+	}
+
+	now := time.Now()
+	started := now.Add(-5 * time.Minute)
+	bridge := &gitlabpkg.BridgeData{
+		Bridge: &gitlab.Bridge{
+			ID:                 789,
+			Name:               "trigger-child",
+			Stage:              "deploy",
+			Status:             "success",
+			StartedAt:          &started,
+			FinishedAt:         &now,
+			DownstreamPipeline: &gitlab.PipelineInfo{ID: 1000, ProjectID: 42},
+		},
+		Raw: map[string]interface{}{},
+	}
+
+	if err := spanExporter.createBridgeSpan(context.Background(), bridge, 0); err != nil {
+		t.Errorf("createBridgeSpan should not error: %v", err)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	if spans[0].Name != "Trigger: trigger-child - bridge_id: 789" {
+		t.Errorf("unexpected span name: %s", spans[0].Name)
+	}
+	if len(spans[0].Links) != 1 || spans[0].Links[0].SpanContext.SpanID() != downstream.SpanID() {
+		t.Errorf("expected link to downstream pipeline span, got %v", spans[0].Links)
+	}
+}
+
+func TestDownstreamSpanContextDeterministic(t *testing.T) {
+	bridge := &gitlabpkg.BridgeData{
+		Bridge: &gitlab.Bridge{
+			ID:                 789,
+			DownstreamPipeline: &gitlab.PipelineInfo{ID: 1000, ProjectID: 42},
+		},
+	}
+
+	spanExporter := &Exporter{config: &config.Config{}}
+	if _, ok := spanExporter.downstreamSpanContext(bridge); ok {
+		t.Error("downstream span context should be unknown without deterministic IDs")
+	}
+
+	spanExporter.config.DeterministicIDs = true
+	if _, ok := spanExporter.downstreamSpanContext(bridge); ok {
+		t.Error("downstream span context should be unknown when the pipeline may join the parent trace")
+	}
+
+	spanExporter.rootPipelines = true
+	spanCtx, ok := spanExporter.downstreamSpanContext(bridge)
+	if !ok {
+		t.Fatal("downstream span context should be computed for pipelines exported as trace roots")
+	}
+	if spanCtx.TraceID() != otelutil.PipelineTraceID(42, 1000) {
+		t.Errorf("unexpected downstream trace ID %s", spanCtx.TraceID())
+	}
+}
+
+func newDownstreamServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	now := time.Now().UTC().Format(time.RFC3339)
+	bridge := func(id, upstream, upstreamProject, downstream, downstreamProject int) map[string]interface{} {
+		return map[string]interface{}{
+			"id": id, "name": fmt.Sprintf("trigger-%d", downstream), "status": "success",
+			"started_at": now, "finished_at": now,
+			"pipeline":            map[string]interface{}{"id": upstream, "project_id": upstreamProject},
+			"downstream_pipeline": map[string]interface{}{"id": downstream, "project_id": downstreamProject},
+		}
+	}
+	pipeline := func(id int, path string) map[string]interface{} {
+		return map[string]interface{}{
+			"id": id, "status": "success", "created_at": now, "updated_at": now,
+			"web_url": fmt.Sprintf("https://gitlab.example.com/%s/-/pipelines/%d", path, id),
+		}
+	}
+	job := func(id int) []map[string]interface{} {
+		return []map[string]interface{}{{"id": id, "name": "build", "status": "success", "started_at": now, "finished_at": now}}
+	}
+
+	routes := map[string]interface{}{
+		"/api/v4/projects/2/pipelines/20":         pipeline(20, "group/child"),
+		"/api/v4/projects/2/pipelines/20/jobs":    job(201),
+		"/api/v4/projects/2/pipelines/20/bridges": []map[string]interface{}{bridge(202, 20, 2, 10, 1), bridge(203, 20, 2, 30, 3)},
+		"/api/v4/projects/3/pipelines/30":         pipeline(30, "group/grandchild"),
+		"/api/v4/projects/3/pipelines/30/jobs":    job(301),
+		"/api/v4/projects/3/pipelines/30/bridges": []map[string]interface{}{bridge(302, 30, 3, 40, 4)},
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := routes[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(body)
+	}))
+}
+
+func TestExportDownstreamPipelineRecursion(t *testing.T) {
+	server := newDownstreamServer(t)
+	defer server.Close()
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSyncer(exporter),
+	)
+	otel.SetTracerProvider(tp)
+	defer func() { _ = tp.Shutdown(context.Background()) }()
+
+	cfg := &config.Config{ServerURL: server.URL, ExportDownstream: true, DownstreamMaxDepth: 2}
+	gitClient, err := gitlabpkg.NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	spanExporter := NewExporter(cfg, gitClient)
+	spanExporter.visited = map[int]bool{10: true}
+
+	bridge := &gitlabpkg.BridgeData{
+		Bridge: &gitlab.Bridge{
+			ID:                 101,
+			Pipeline:           gitlab.PipelineInfo{ID: 10, ProjectID: 1},
+			DownstreamPipeline: &gitlab.PipelineInfo{ID: 20, ProjectID: 2},
+		},
+	}
+	spanExporter.exportDownstreamPipeline(context.Background(), bridge, 1)
+
+	names := map[string]bool{}
+	for _, span := range exporter.GetSpans() {
+		names[span.Name] = true
+	}
+
+	for _, want := range []string{
+		"group/child #20",
+		"group/grandchild #30",
+		"Stage: build - job_id: 201",
+		"Stage: build - job_id: 301",
+		"Trigger: trigger-30 - bridge_id: 203",
+		"Trigger: trigger-40 - bridge_id: 302",
+	} {
+		if !names[want] {
+			t.Errorf("expected span %q, got %v", want, names)
+		}
+	}
+	if len(names) != 7 {
+		t.Errorf("expected 7 spans (cycle and depth limit stop recursion), got %d: %v", len(names), names)
+	}
+}
+
 func TestDownstreamPipelineIntegration(t *testing.T) {
-	// Simulate downstream pipeline environment
-	_ = os.Setenv("CI_PIPELINE_SOURCE", "pipeline")
-	_ = os.Setenv("CI_PARENT_PIPELINE_ID", "100")
-	_ = os.Setenv("CI_PARENT_PROJECT_ID", "200")
-	_ = os.Setenv("TRACEPARENT", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
-	_ = os.Setenv("CI_PROJECT_NAMESPACE", "test")
-	_ = os.Setenv("CI_PROJECT_NAME", "downstream")
-	defer func() {
-		_ = os.Unsetenv("CI_PIPELINE_SOURCE")
-		_ = os.Unsetenv("CI_PARENT_PIPELINE_ID")
-		_ = os.Unsetenv("CI_PARENT_PROJECT_ID")
-		_ = os.Unsetenv("TRACEPARENT")
-		_ = os.Unsetenv("CI_PROJECT_NAMESPACE")
-		_ = os.Unsetenv("CI_PROJECT_NAME")
-	}()
+	// Simulate downstream pipeline configuration
+	cfg := &config.Config{
+		PipelineSource:   "pipeline",
+		ParentPipelineID: "100",
+		ParentProjectID:  "200",
+		TraceParent:      "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		ProjectNamespace: "test",
+		ProjectName:      "downstream",
+	}
 
 	// Test pipeline attributes include parent info
-	attrs := semconv.PipelineAttributes()
+	attrs := semconv.PipelineAttributes(cfg)
 	found := false
 	for _, attr := range attrs {
 		if attr.Key == "cicd.pipeline.trigger.type" && attr.Value.AsString() == "other_pipeline" {
@@ -138,8 +362,313 @@ func TestDownstreamPipelineIntegration(t *testing.T) {
 			User: &gitlab.BasicUser{ID: 300},
 		},
 	}
-	parentAttrs := semconv.ParentPipelineAttributes(nil, pipeline)
+	parentAttrs := semconv.ParentPipelineAttributes(cfg, pipeline)
 	if len(parentAttrs) == 0 {
 		t.Error("downstream pipeline should have parent attributes")
 	}
 }
+
+func TestExportPipelineFromCapture(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSyncer(exporter),
+	)
+	otel.SetTracerProvider(tp)
+	defer func() { _ = tp.Shutdown(context.Background()) }()
+
+	cfg := &config.Config{ProjectID: "group/app", PipelineID: "100", ProjectNamespace: "group", ProjectName: "app"}
+	spanExporter := NewExporter(cfg, gitlabpkg.NewFileSource("testdata/capture"))
+	if err := spanExporter.ExportPipeline(context.Background()); err != nil {
+		t.Fatalf("ExportPipeline failed: %v", err)
+	}
+
+	spans := exporter.GetSpans()
+	byName := map[string]tracetest.SpanStub{}
+	for _, span := range spans {
+		byName[span.Name] = span
+	}
+	pipeline, ok := byName["group/app #100"]
+	if !ok {
+		t.Fatalf("expected a pipeline span, got %d spans", len(spans))
+	}
+	if pipeline.Status.Code != codes.Error {
+		t.Errorf("expected failed pipeline to have error status, got %v", pipeline.Status.Code)
+	}
+
+	// The skipped deploy job has no span, the jobs that ran have a queued
+	// span each
+	for _, name := range []string{"Stage: compile - job_id: 201", "Stage: unit - job_id: 202"} {
+		job, ok := byName[name]
+		if !ok {
+			t.Errorf("expected span %q", name)
+			continue
+		}
+		if job.Parent.SpanID() != pipeline.SpanContext.SpanID() {
+			t.Errorf("expected %q to be a child of the pipeline span", name)
+		}
+	}
+	if len(spans) != 5 {
+		t.Errorf("expected 5 spans, got %d", len(spans))
+	}
+}
+
+func TestExportPipelineEndToEnd(t *testing.T) {
+	api := gitlabtest.NewServer(t)
+	created := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	at := func(seconds int) *time.Time {
+		ts := created.Add(time.Duration(seconds) * time.Second)
+		return &ts
+	}
+	api.AddPipeline(&gitlab.Pipeline{
+		ID: 500, ProjectID: 7, Status: "success", Source: "pipeline", Ref: "main",
+		WebURL:    "https://gitlab.example.com/group/app/-/pipelines/500",
+		CreatedAt: at(0), UpdatedAt: at(300),
+	})
+	api.AddJobs(500,
+		&gitlab.Job{ID: 501, Name: "compile", Stage: "build", Status: "success", CreatedAt: at(0), StartedAt: at(0), FinishedAt: at(100)},
+		&gitlab.Job{ID: 502, Name: "unit", Stage: "test", Status: "success", CreatedAt: at(0), StartedAt: at(110), FinishedAt: at(200)},
+	)
+	api.AddBridges(500, &gitlab.Bridge{
+		ID: 503, Name: "deploy", Stage: "deploy", Status: "success",
+		Pipeline:           gitlab.PipelineInfo{ID: 500, ProjectID: 7},
+		DownstreamPipeline: &gitlab.PipelineInfo{ID: 600, ProjectID: 8},
+		StartedAt:          at(210), FinishedAt: at(290),
+	})
+	api.AddPipeline(&gitlab.Pipeline{
+		ID: 600, ProjectID: 8, Status: "success", Source: "parent_pipeline", Ref: "main",
+		WebURL:    "https://gitlab.example.com/group/deployer/-/pipelines/600",
+		CreatedAt: at(220), UpdatedAt: at(280),
+	})
+	api.AddJobs(600, &gitlab.Job{ID: 601, Name: "rollout", Stage: "rollout", Status: "success", CreatedAt: at(220), StartedAt: at(230), FinishedAt: at(270)})
+	api.SetVariable(500, "TRACEPARENT", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	api.SetJobTrace(501, "section_start:1772355600:build\r\x1b[0K\nsection_end:1772355690:build\r\x1b[0K\n")
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSyncer(exporter),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer func() { _ = tp.Shutdown(context.Background()) }()
+
+	cfg := api.Config()
+	cfg.ProjectID = "group/app"
+	cfg.PipelineID = "500"
+	cfg.ProjectNamespace = "group"
+	cfg.ProjectName = "app"
+	cfg.PipelineSource = "pipeline"
+	cfg.StageSpans = true
+	cfg.JobSections = true
+	cfg.ExportDownstream = true
+	gitClient, err := gitlabpkg.NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	if err := NewExporter(cfg, gitClient).ExportPipeline(context.Background()); err != nil {
+		t.Fatalf("ExportPipeline failed: %v", err)
+	}
+
+	byName := map[string]tracetest.SpanStub{}
+	for _, span := range exporter.GetSpans() {
+		byName[span.Name] = span
+	}
+	parentOf := func(child, parent string) {
+		t.Helper()
+		c, ok := byName[child]
+		if !ok {
+			t.Errorf("missing span %q", child)
+			return
+		}
+		if p := byName[parent]; c.Parent.SpanID() != p.SpanContext.SpanID() {
+			t.Errorf("expected %q to be a child of %q", child, parent)
+		}
+	}
+
+	pipeline, ok := byName["group/app #500"]
+	if !ok {
+		t.Fatalf("missing pipeline span, got %d spans", len(byName))
+	}
+	if got := pipeline.SpanContext.TraceID().String(); got != "0af7651916cd43dd8448eb211c80319c" {
+		t.Errorf("expected the pipeline to continue the trace of its TRACEPARENT variable, got trace %s", got)
+	}
+	parentOf("Stage: build", "group/app #500")
+	parentOf("Stage: test", "group/app #500")
+	parentOf("Stage: deploy", "group/app #500")
+	parentOf("Stage: compile - job_id: 501", "Stage: build")
+	parentOf("Stage: unit - job_id: 502", "Stage: test")
+	parentOf("Trigger: deploy - bridge_id: 503", "Stage: deploy")
+	parentOf("build", "Stage: compile - job_id: 501")
+	parentOf("group/deployer #600", "Trigger: deploy - bridge_id: 503")
+	parentOf("Stage: rollout - job_id: 601", "Stage: rollout")
+	parentOf("Stage: rollout", "group/deployer #600")
+}
+
+// hangingSource never answers job requests before the context is done
+type hangingSource struct {
+	gitlabpkg.PipelineSource
+}
+
+func (s hangingSource) FetchJobsByID(ctx context.Context, projectID string, pipelineID int) ([]*gitlabpkg.JobData, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestExportPipelineDeadline(t *testing.T) {
+	api := gitlabtest.NewServer(t)
+	api.AddPipeline(&gitlab.Pipeline{ID: 500, ProjectID: 7, Status: "success"})
+
+	cfg := api.Config()
+	cfg.ProjectID = "7"
+	cfg.PipelineID = "500"
+	cfg.ExportTimeout = 50 * time.Millisecond
+	gitClient, err := gitlabpkg.NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	err = NewExporter(cfg, hangingSource{gitClient}).ExportPipeline(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "export-timeout") {
+		t.Errorf("expected the export to fail at the export timeout, got %v", err)
+	}
+}
+
+func TestExportPipelineRetriedJobs(t *testing.T) {
+	api := gitlabtest.NewServer(t)
+	created := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	at := func(seconds int) *time.Time {
+		ts := created.Add(time.Duration(seconds) * time.Second)
+		return &ts
+	}
+	api.AddPipeline(&gitlab.Pipeline{ID: 500, ProjectID: 7, Status: "success", CreatedAt: at(0), UpdatedAt: at(300)})
+	api.AddJobs(500,
+		&gitlab.Job{ID: 502, Name: "unit", Stage: "test", Status: "success", StartedAt: at(100), FinishedAt: at(200)},
+		&gitlab.Job{ID: 501, Name: "unit", Stage: "test", Status: "failed", StartedAt: at(0), FinishedAt: at(90)},
+	)
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSyncer(exporter),
+	)
+	otel.SetTracerProvider(tp)
+	defer func() { _ = tp.Shutdown(context.Background()) }()
+
+	cfg := api.Config()
+	cfg.ProjectID = "7"
+	cfg.PipelineID = "500"
+	cfg.StageSpans = true
+	gitClient, err := gitlabpkg.NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	if err := NewExporter(cfg, gitClient).ExportPipeline(context.Background()); err != nil {
+		t.Fatalf("ExportPipeline failed: %v", err)
+	}
+
+	byName := map[string]tracetest.SpanStub{}
+	for _, span := range exporter.GetSpans() {
+		byName[span.Name] = span
+	}
+	first, retry := byName["Stage: unit - job_id: 501"], byName["Stage: unit - job_id: 502"]
+
+	attempt := func(span tracetest.SpanStub) int64 {
+		for _, attr := range span.Attributes {
+			if attr.Key == "cicd.pipeline.task.run.attempt" {
+				return attr.Value.AsInt64()
+			}
+		}
+		return 0
+	}
+	if attempt(first) != 1 || attempt(retry) != 2 {
+		t.Errorf("expected attempts 1 and 2, got %d and %d", attempt(first), attempt(retry))
+	}
+	if len(retry.Links) != 1 || retry.Links[0].SpanContext.SpanID() != first.SpanContext.SpanID() {
+		t.Errorf("expected the retry to link to the first attempt, got %d links", len(retry.Links))
+	}
+	if len(first.Links) != 0 {
+		t.Errorf("expected no links on the first attempt, got %d", len(first.Links))
+	}
+	if stage := byName["Stage: test"]; stage.Status.Code != codes.Ok {
+		t.Errorf("expected the stage to pass as its job passed on retry, got %v", stage.Status.Code)
+	}
+}
+
+func TestExportDownstreamDeterministicLinks(t *testing.T) {
+	api := gitlabtest.NewServer(t)
+	created := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	at := func(seconds int) *time.Time {
+		ts := created.Add(time.Duration(seconds) * time.Second)
+		return &ts
+	}
+	for _, p := range []struct{ id, project int }{{800, 7}, {900, 8}, {950, 9}} {
+		api.AddPipeline(&gitlab.Pipeline{
+			ID: p.id, ProjectID: p.project, Status: "success",
+			WebURL:    fmt.Sprintf("https://gitlab.example.com/group/app-%d/-/pipelines/%d", p.project, p.id),
+			CreatedAt: at(0), UpdatedAt: at(300),
+		})
+		api.AddJobs(p.id, &gitlab.Job{ID: p.id + 1, Name: "build", Stage: "build", Status: "success", StartedAt: at(10), FinishedAt: at(20)})
+	}
+	api.AddBridges(800, &gitlab.Bridge{
+		ID: 802, Name: "child", Stage: "deploy", Status: "success",
+		Pipeline:           gitlab.PipelineInfo{ID: 800, ProjectID: 7},
+		DownstreamPipeline: &gitlab.PipelineInfo{ID: 900, ProjectID: 8},
+		StartedAt:          at(30), FinishedAt: at(200),
+	})
+	api.AddBridges(900, &gitlab.Bridge{
+		ID: 902, Name: "grandchild", Stage: "deploy", Status: "success",
+		Pipeline:           gitlab.PipelineInfo{ID: 900, ProjectID: 8},
+		DownstreamPipeline: &gitlab.PipelineInfo{ID: 950, ProjectID: 9},
+		StartedAt:          at(40), FinishedAt: at(150),
+	})
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSyncer(exporter),
+		sdktrace.WithIDGenerator(otelutil.IDGenerator{}),
+	)
+	otel.SetTracerProvider(tp)
+	defer func() { _ = tp.Shutdown(context.Background()) }()
+
+	cfg := api.Config()
+	cfg.DeterministicIDs = true
+	cfg.ExportDownstream = true
+	gitClient, err := gitlabpkg.NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	if err := NewExporter(cfg, gitClient).ExportPipelineByID(context.Background(), "7", 800); err != nil {
+		t.Fatalf("ExportPipelineByID failed: %v", err)
+	}
+
+	type spanRef struct {
+		traceID trace.TraceID
+		spanID  trace.SpanID
+	}
+	exported := map[spanRef]string{}
+	for _, span := range exporter.GetSpans() {
+		exported[spanRef{span.SpanContext.TraceID(), span.SpanContext.SpanID()}] = span.Name
+	}
+	bridges := 0
+	for _, span := range exporter.GetSpans() {
+		if !strings.HasPrefix(span.Name, "Trigger: ") {
+			continue
+		}
+		bridges++
+		if len(span.Links) != 1 {
+			t.Errorf("expected %q to link to its downstream pipeline, got %d links", span.Name, len(span.Links))
+			continue
+		}
+		link := span.Links[0].SpanContext
+		target, ok := exported[spanRef{link.TraceID(), link.SpanID()}]
+		if !ok {
+			t.Errorf("%q links to a span that was not exported", span.Name)
+			continue
+		}
+		if !strings.HasPrefix(target, "group/app-") {
+			t.Errorf("expected %q to link to a pipeline span, got %q", span.Name, target)
+		}
+	}
+	if bridges != 2 {
+		t.Errorf("expected 2 bridge spans, got %d", bridges)
+	}
+}
@@ -0,0 +1,88 @@
+package spans
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+
+	otellog "go.opentelemetry.io/otel/log"
+	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/gitlab"
+	otelutil "gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/otel"
+)
+
+// exportJobLog downloads the job log once and feeds it to the section span
+// and log record exports that are enabled
+func (e *Exporter) exportJobLog(ctx context.Context, job *gitlab.JobData) {
+	projectID := e.config.ProjectID
+	if job.Pipeline.ProjectID != 0 {
+		projectID = strconv.Itoa(job.Pipeline.ProjectID)
+	}
+
+	jobLog, err := e.source.FetchJobTrace(ctx, projectID, job.ID)
+	if err != nil {
+		log.Printf("failed to fetch log of job %d: %v", job.ID, err)
+		return
+	}
+	data, err := io.ReadAll(jobLog)
+	if err != nil {
+		log.Printf("failed to read log of job %d: %v", job.ID, err)
+		return
+	}
+
+	if e.config.JobSections {
+		e.createSectionSpans(ctx, job, bytes.NewReader(data))
+	}
+	if e.config.ExportLogs {
+		e.emitLogRecords(ctx, job, data)
+	}
+}
+
+// emitLogRecords emits one log record per non-empty job log line, correlated
+// with the job span in ctx. Records are timestamped with the latest section
+// marker seen, as GitLab logs carry no per-line timestamps. Only the last
+// LogTailLines lines are exported when set, and export stops once
+// LogMaxBytes of log text have been emitted.
+func (e *Exporter) emitLogRecords(ctx context.Context, job *gitlab.JobData, data []byte) {
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	first := 0
+	if e.config.LogTailLines > 0 && len(lines) > e.config.LogTailLines {
+		first = len(lines) - e.config.LogTailLines
+	}
+
+	at := *job.StartedAt
+	emitted, size := 0, 0
+	for i, line := range lines {
+		text, markerAt, ok := gitlab.ParseLogLine(line)
+		if ok {
+			at = markerAt
+		}
+		if i < first || strings.TrimSpace(text) == "" {
+			continue
+		}
+		if e.config.LogMaxBytes > 0 && size+len(text) > e.config.LogMaxBytes {
+			log.Printf("log of job %d truncated after %d bytes (LOG_MAX_BYTES=%d)", job.ID, size, e.config.LogMaxBytes)
+			break
+		}
+		size += len(text)
+
+		severity := otelutil.InferSeverity(text)
+		var record otellog.Record
+		record.SetTimestamp(at)
+		record.SetBody(otellog.StringValue(text))
+		record.SetSeverity(severity)
+		record.SetSeverityText(severity.String())
+		record.AddAttributes(
+			otellog.String("cicd.pipeline.task.name", job.Name),
+			otellog.String("cicd.pipeline.task.run.id", fmt.Sprintf("%d", job.ID)),
+			otellog.Int("cicd.pipeline.task.log.line", i+1),
+		)
+		e.logger.Emit(ctx, record)
+		emitted++
+	}
+
+	fmt.Printf("    Exported %d log lines (%d bytes)\n", emitted, size)
+}
@@ -0,0 +1,106 @@
+package spans
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+	"go.opentelemetry.io/otel"
+	otellog "go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/config"
+	gitlabpkg "gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/gitlab"
+)
+
+// recordingLogExporter keeps exported log records in memory
+type recordingLogExporter struct {
+	mu      sync.Mutex
+	records []sdklog.Record
+}
+
+func (r *recordingLogExporter) Export(_ context.Context, records []sdklog.Record) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, record := range records {
+		r.records = append(r.records, record.Clone())
+	}
+	return nil
+}
+
+func (r *recordingLogExporter) Shutdown(context.Context) error   { return nil }
+func (r *recordingLogExporter) ForceFlush(context.Context) error { return nil }
+
+func newLogTestExporter(cfg *config.Config) (*Exporter, *recordingLogExporter, trace.Span) {
+	recorder := &recordingLogExporter{}
+	lp := sdklog.NewLoggerProvider(sdklog.WithProcessor(sdklog.NewSimpleProcessor(recorder)))
+	tp := sdktrace.NewTracerProvider()
+	otel.SetTracerProvider(tp)
+
+	spanExporter := &Exporter{
+		config: cfg,
+		tracer: otel.Tracer("test"),
+		logger: lp.Logger("test"),
+	}
+	_, span := tp.Tracer("test").Start(context.Background(), "job")
+	return spanExporter, recorder, span
+}
+
+func TestEmitLogRecords(t *testing.T) {
+	spanExporter, recorder, span := newLogTestExporter(&config.Config{})
+	defer span.End()
+
+	started := time.Unix(900, 0)
+	job := &gitlabpkg.JobData{Job: &gitlab.Job{ID: 123, Name: "build", StartedAt: &started}}
+	data := []byte("Running with gitlab-runner\n" +
+		"section_start:1000:step_script\r\x1b[0KExecuting step_script\n" +
+		"\n" +
+		"\x1b[31mERROR: something broke\x1b[0m\n")
+
+	ctx := trace.ContextWithSpan(context.Background(), span)
+	spanExporter.emitLogRecords(ctx, job, data)
+
+	if len(recorder.records) != 3 {
+		t.Fatalf("expected 3 log records, got %d", len(recorder.records))
+	}
+
+	first, last := recorder.records[0], recorder.records[2]
+	if !first.Timestamp().Equal(started) {
+		t.Errorf("lines before any section should use the job start time, got %v", first.Timestamp())
+	}
+	if !last.Timestamp().Equal(time.Unix(1000, 0)) {
+		t.Errorf("lines after a section marker should use its time, got %v", last.Timestamp())
+	}
+	if last.Body().AsString() != "ERROR: something broke" {
+		t.Errorf("log body should be stripped of ANSI codes, got %q", last.Body().AsString())
+	}
+	if last.Severity() != otellog.SeverityError {
+		t.Errorf("expected error severity, got %v", last.Severity())
+	}
+	if last.SpanID() != span.SpanContext().SpanID() || last.TraceID() != span.SpanContext().TraceID() {
+		t.Error("log records should be correlated with the job span")
+	}
+}
+
+func TestEmitLogRecordsTailAndCap(t *testing.T) {
+	started := time.Unix(900, 0)
+	job := &gitlabpkg.JobData{Job: &gitlab.Job{ID: 123, StartedAt: &started}}
+	data := []byte("line-1\nline-2\nline-3\nline-4\n")
+
+	spanExporter, recorder, span := newLogTestExporter(&config.Config{LogTailLines: 2})
+	spanExporter.emitLogRecords(context.Background(), job, data)
+	span.End()
+	if len(recorder.records) != 2 || recorder.records[0].Body().AsString() != "line-3" {
+		t.Errorf("expected only the last 2 lines, got %d records", len(recorder.records))
+	}
+
+	spanExporter, recorder, span = newLogTestExporter(&config.Config{LogMaxBytes: 13})
+	spanExporter.emitLogRecords(context.Background(), job, data)
+	span.End()
+	if len(recorder.records) != 2 {
+		t.Errorf("expected the byte cap to stop after 2 lines, got %d records", len(recorder.records))
+	}
+}
@@ -0,0 +1,70 @@
+package spans
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/metric"
+	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/gitlab"
+	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/pkg/semconv"
+)
+
+// pipelineMetrics holds the instruments recorded for exported pipelines and jobs
+type pipelineMetrics struct {
+	pipelineDuration metric.Float64Histogram
+	pipelineQueued   metric.Float64Histogram
+	jobDuration      metric.Float64Histogram
+	jobQueued        metric.Float64Histogram
+	jobCount         metric.Int64Counter
+}
+
+func newPipelineMetrics(meter metric.Meter) (*pipelineMetrics, error) {
+	m := &pipelineMetrics{}
+	var err error
+
+	if m.pipelineDuration, err = meter.Float64Histogram("cicd.pipeline.run.duration",
+		metric.WithDescription("Duration of a pipeline run"), metric.WithUnit("s")); err != nil {
+		return nil, err
+	}
+	if m.pipelineQueued, err = meter.Float64Histogram("cicd.pipeline.run.queue.duration",
+		metric.WithDescription("Time a pipeline run waited before starting"), metric.WithUnit("s")); err != nil {
+		return nil, err
+	}
+	if m.jobDuration, err = meter.Float64Histogram("cicd.pipeline.task.run.duration",
+		metric.WithDescription("Duration of a job run"), metric.WithUnit("s")); err != nil {
+		return nil, err
+	}
+	if m.jobQueued, err = meter.Float64Histogram("cicd.pipeline.task.run.queue.duration",
+		metric.WithDescription("Time a job waited for a runner"), metric.WithUnit("s")); err != nil {
+		return nil, err
+	}
+	if m.jobCount, err = meter.Int64Counter("cicd.pipeline.task.run.count",
+		metric.WithDescription("Number of job runs by status, stage and runner"), metric.WithUnit("{run}")); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func (m *pipelineMetrics) recordPipeline(ctx context.Context, pipeline *gitlab.PipelineData) {
+	attrs := metric.WithAttributes(semconv.PipelineMetricAttributes(pipeline)...)
+
+	duration := float64(pipeline.Duration)
+	if duration == 0 && pipeline.StartedAt != nil && pipeline.FinishedAt != nil {
+		duration = pipeline.FinishedAt.Sub(*pipeline.StartedAt).Seconds()
+	}
+	if duration > 0 {
+		m.pipelineDuration.Record(ctx, duration, attrs)
+	}
+	m.pipelineQueued.Record(ctx, float64(pipeline.QueuedDuration), attrs)
+}
+
+func (m *pipelineMetrics) recordJob(ctx context.Context, job *gitlab.JobData) {
+	attrs := metric.WithAttributes(semconv.JobMetricAttributes(job)...)
+
+	m.jobCount.Add(ctx, 1, attrs)
+	if job.StartedAt == nil {
+		return
+	}
+	m.jobDuration.Record(ctx, job.Duration, attrs)
+	m.jobQueued.Record(ctx, job.QueuedDuration, attrs)
+}
@@ -0,0 +1,75 @@
+package spans
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	gitlabpkg "gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/gitlab"
+)
+
+func TestPipelineMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	defer func() { _ = mp.Shutdown(context.Background()) }()
+
+	metrics, err := newPipelineMetrics(mp.Meter("test"))
+	if err != nil {
+		t.Fatalf("newPipelineMetrics failed: %v", err)
+	}
+
+	ctx := context.Background()
+	metrics.recordPipeline(ctx, &gitlabpkg.PipelineData{
+		Pipeline: &gitlab.Pipeline{ID: 1, Status: "success", Duration: 120, QueuedDuration: 5},
+	})
+
+	started := time.Now()
+	for _, status := range []string{"success", "failed", "failed"} {
+		metrics.recordJob(ctx, &gitlabpkg.JobData{
+			Job: &gitlab.Job{Name: "test", Stage: "test", Status: status, StartedAt: &started, Duration: 30, QueuedDuration: 2},
+		})
+	}
+	metrics.recordJob(ctx, &gitlabpkg.JobData{
+		Job: &gitlab.Job{Name: "deploy", Stage: "deploy", Status: "skipped"},
+	})
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(ctx, &rm); err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+
+	found := map[string]metricdata.Aggregation{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			found[m.Name] = m.Data
+		}
+	}
+
+	duration, ok := found["cicd.pipeline.run.duration"].(metricdata.Histogram[float64])
+	if !ok || len(duration.DataPoints) != 1 || duration.DataPoints[0].Sum != 120 {
+		t.Errorf("unexpected pipeline duration histogram: %+v", found["cicd.pipeline.run.duration"])
+	}
+
+	jobDuration, ok := found["cicd.pipeline.task.run.duration"].(metricdata.Histogram[float64])
+	if !ok || len(jobDuration.DataPoints) != 2 {
+		t.Errorf("job duration should only be recorded for started jobs, one series per status: %+v", found["cicd.pipeline.task.run.duration"])
+	}
+
+	count, ok := found["cicd.pipeline.task.run.count"].(metricdata.Sum[int64])
+	if !ok {
+		t.Fatalf("missing job count metric")
+	}
+	total := int64(0)
+	for _, dp := range count.DataPoints {
+		total += dp.Value
+		if status, _ := dp.Attributes.Value("cicd.pipeline.task.run.status"); status.AsString() == "failed" && dp.Value != 2 {
+			t.Errorf("expected 2 failed jobs, got %d", dp.Value)
+		}
+	}
+	if total != 4 {
+		t.Errorf("expected 4 counted jobs, got %d", total)
+	}
+}
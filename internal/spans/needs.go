@@ -0,0 +1,113 @@
+package spans
+
+import (
+	"context"
+	"log"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/gitlab"
+)
+
+// applyJobNeeds fetches which jobs each job of a pipeline needs. A pipeline
+// whose needs cannot be read is exported without links.
+func (e *Exporter) applyJobNeeds(ctx context.Context, pipeline *gitlab.PipelineData, jobs []*gitlab.JobData) {
+	needs, err := e.source.FetchJobNeeds(ctx, pipeline)
+	if err != nil {
+		log.Printf("failed to fetch job needs of pipeline %d, continuing without needs links: %v", pipeline.ID, err)
+		return
+	}
+	needs.Apply(jobs)
+}
+
+// needLinks links a job span to the spans of the jobs it needs, which have
+// to be created first
+func (e *Exporter) needLinks(job *gitlab.JobData) []trace.Link {
+	var links []trace.Link
+	for _, need := range job.Needs {
+		needed, ok := e.jobSpans[need.JobID]
+		if !ok || need.JobID == 0 {
+			continue
+		}
+		links = append(links, trace.Link{
+			SpanContext: needed,
+			Attributes: []attribute.KeyValue{
+				attribute.String("cicd.pipeline.link.type", "needs"),
+				attribute.String("cicd.pipeline.task.name", need.Name),
+			},
+		})
+	}
+	return links
+}
+
+// jobsInNeedOrder orders jobs after the jobs they need and after their
+// previous attempt, so every span a job links to exists when it is created
+func jobsInNeedOrder(jobs []*gitlab.JobData) []*gitlab.JobData {
+	byID := map[int]*gitlab.JobData{}
+	for _, job := range jobs {
+		byID[job.ID] = job
+	}
+	return orderAfter(jobs, func(job *gitlab.JobData) []*gitlab.JobData {
+		var deps []*gitlab.JobData
+		if previous, ok := byID[job.PreviousAttemptID]; ok {
+			deps = append(deps, previous)
+		}
+		for _, need := range job.Needs {
+			if needed, ok := byID[need.JobID]; ok {
+				deps = append(deps, needed)
+			}
+		}
+		return deps
+	})
+}
+
+// stagesInNeedOrder orders stages after the stages holding jobs their jobs
+// need. With needs, a stage can start before a stage it depends on, when one
+// of its jobs needs nothing.
+func stagesInNeedOrder(stages []*stageGroup) []*stageGroup {
+	stageOf := map[int]*stageGroup{}
+	for _, stage := range stages {
+		for _, job := range stage.jobs {
+			stageOf[job.ID] = stage
+		}
+	}
+	return orderAfter(stages, func(stage *stageGroup) []*stageGroup {
+		var deps []*stageGroup
+		for _, job := range stage.jobs {
+			for _, need := range job.Needs {
+				if needed, ok := stageOf[need.JobID]; ok && needed != stage {
+					deps = append(deps, needed)
+				}
+			}
+		}
+		return deps
+	})
+}
+
+// orderAfter orders items after the items they depend on, keeping the given
+// order where dependencies allow. Dependencies outside items are ignored, and
+// cycles, which GitLab rejects, are broken in the given order.
+func orderAfter[T comparable](items []T, deps func(T) []T) []T {
+	inItems := map[T]bool{}
+	for _, item := range items {
+		inItems[item] = true
+	}
+
+	ordered := make([]T, 0, len(items))
+	visited := map[T]bool{}
+	var visit func(T)
+	visit = func(item T) {
+		if visited[item] || !inItems[item] {
+			return
+		}
+		visited[item] = true
+		for _, dep := range deps(item) {
+			visit(dep)
+		}
+		ordered = append(ordered, item)
+	}
+	for _, item := range items {
+		visit(item)
+	}
+	return ordered
+}
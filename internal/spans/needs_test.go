@@ -0,0 +1,90 @@
+package spans
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	gitlabpkg "gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/gitlab"
+	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/gitlab/gitlabtest"
+)
+
+func TestExportPipelineNeedsLinks(t *testing.T) {
+	api := gitlabtest.NewServer(t)
+	created := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	at := func(seconds int) *time.Time {
+		ts := created.Add(time.Duration(seconds) * time.Second)
+		return &ts
+	}
+	api.AddPipeline(&gitlab.Pipeline{
+		ID: 700, IID: 12, ProjectID: 7, Status: "success",
+		WebURL:    "https://gitlab.example.com/group/app/-/pipelines/700",
+		CreatedAt: at(0), UpdatedAt: at(400),
+	})
+	// The test stage starts first, with a lint job that needs nothing, and
+	// report needs unit although it was created first
+	api.AddJobs(700,
+		&gitlab.Job{ID: 701, Name: "report", Stage: "test", Status: "success", CreatedAt: at(0), StartedAt: at(300), FinishedAt: at(310)},
+		&gitlab.Job{ID: 702, Name: "compile", Stage: "build", Status: "success", CreatedAt: at(0), StartedAt: at(10), FinishedAt: at(100)},
+		&gitlab.Job{ID: 703, Name: "lint", Stage: "test", Status: "success", CreatedAt: at(0), StartedAt: at(0), FinishedAt: at(50)},
+		&gitlab.Job{ID: 704, Name: "unit", Stage: "test", Status: "success", CreatedAt: at(0), StartedAt: at(110), FinishedAt: at(200)},
+	)
+	api.SetNeeds(700, "lint")
+	api.SetNeeds(700, "unit", "compile")
+	api.SetNeeds(700, "report", "unit")
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSyncer(exporter),
+	)
+	otel.SetTracerProvider(tp)
+	defer func() { _ = tp.Shutdown(context.Background()) }()
+
+	cfg := api.Config()
+	cfg.ProjectID = "group/app"
+	cfg.PipelineID = "700"
+	cfg.StageSpans = true
+	cfg.JobNeeds = true
+	gitClient, err := gitlabpkg.NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	if err := NewExporter(cfg, gitClient).ExportPipeline(context.Background()); err != nil {
+		t.Fatalf("ExportPipeline failed: %v", err)
+	}
+
+	byJob := map[string]tracetest.SpanStub{}
+	for _, span := range exporter.GetSpans() {
+		if attributeOf(span.Attributes, "cicd.pipeline.task.run.id") != "" {
+			byJob[attributeOf(span.Attributes, "cicd.pipeline.task.name")] = span
+		}
+	}
+	linksTo := func(job, needed string) {
+		t.Helper()
+		links := byJob[job].Links
+		if len(links) != 1 {
+			t.Fatalf("expected %s to have one link, got %d", job, len(links))
+		}
+		if links[0].SpanContext.SpanID() != byJob[needed].SpanContext.SpanID() {
+			t.Errorf("expected %s to link to %s", job, needed)
+		}
+		if got := attributeOf(links[0].Attributes, "cicd.pipeline.link.type"); got != "needs" {
+			t.Errorf("expected link type needs, got %q", got)
+		}
+		if got := attributeOf(links[0].Attributes, "cicd.pipeline.task.name"); got != needed {
+			t.Errorf("expected the link to name %s, got %q", needed, got)
+		}
+	}
+	if len(byJob) != 4 {
+		t.Fatalf("expected 4 job spans, got %d", len(byJob))
+	}
+	linksTo("unit", "compile")
+	linksTo("report", "unit")
+	if links := byJob["lint"].Links; len(links) != 0 {
+		t.Errorf("expected lint to have no links, got %d", len(links))
+	}
+}
@@ -0,0 +1,44 @@
+package spans
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/gitlab"
+	otelutil "gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/otel"
+)
+
+// createSectionSpans turns the sections of a job log into nested child spans
+// of the job span
+func (e *Exporter) createSectionSpans(ctx context.Context, job *gitlab.JobData, jobLog io.Reader) {
+	sections, err := gitlab.ParseSections(jobLog)
+	if err != nil {
+		log.Printf("failed to parse log sections of job %d: %v", job.ID, err)
+		return
+	}
+
+	index := 0
+	e.createSectionSpansFrom(ctx, job, sections, &index)
+}
+
+func (e *Exporter) createSectionSpansFrom(ctx context.Context, job *gitlab.JobData, sections []*gitlab.Section, index *int) {
+	for _, section := range sections {
+		key := otelutil.JobChildIDKey(job.ID, fmt.Sprintf("section/%d/%s", *index, section.Name))
+		*index++
+
+		sectionCtx, sectionSpan := e.startSpan(ctx, key, section.Name,
+			trace.WithTimestamp(section.Start),
+			trace.WithSpanKind(trace.SpanKindInternal),
+			trace.WithAttributes(
+				attribute.String("cicd.pipeline.task.section.name", section.Name),
+				attribute.Bool("cicd.pipeline.task.section.collapsed", section.Collapsed),
+			),
+		)
+		e.createSectionSpansFrom(sectionCtx, job, section.Children, index)
+		sectionSpan.End(trace.WithTimestamp(section.End))
+	}
+}
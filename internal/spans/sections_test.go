@@ -0,0 +1,84 @@
+package spans
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/config"
+	gitlabpkg "gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/gitlab"
+)
+
+func TestCreateJobSpanWithSections(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v4/projects/1/jobs/123/trace" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte("section_start:1000:get_sources\r\x1b[0K\n" +
+			"section_end:1002:get_sources\r\x1b[0K\n" +
+			"section_start:1002:step_script\r\x1b[0K\n" +
+			"section_start:1003:restore_cache\r\x1b[0K\n" +
+			"section_end:1004:restore_cache\r\x1b[0K\n" +
+			"section_end:1010:step_script\r\x1b[0K\n"))
+	}))
+	defer server.Close()
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSyncer(exporter),
+	)
+	otel.SetTracerProvider(tp)
+	defer func() { _ = tp.Shutdown(context.Background()) }()
+
+	cfg := &config.Config{ServerURL: server.URL, ProjectID: "1", JobSections: true}
+	gitClient, err := gitlabpkg.NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	spanExporter := NewExporter(cfg, gitClient)
+
+	started := time.Unix(1000, 0)
+	finished := time.Unix(1010, 0)
+	job := &gitlabpkg.JobData{
+		Job: &gitlab.Job{
+			ID:         123,
+			Name:       "build",
+			Status:     "success",
+			StartedAt:  &started,
+			FinishedAt: &finished,
+		},
+		Raw: map[string]interface{}{},
+	}
+
+	if err := spanExporter.createJobSpan(context.Background(), job); err != nil {
+		t.Errorf("createJobSpan should not error: %v", err)
+	}
+
+	byName := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range exporter.GetSpans().Snapshots() {
+		byName[span.Name()] = span
+	}
+	if len(byName) != 4 {
+		t.Fatalf("expected job span and 3 section spans, got %v", byName)
+	}
+
+	jobSpan := byName["Stage: build - job_id: 123"]
+	script := byName["step_script"]
+	cache := byName["restore_cache"]
+	if byName["get_sources"].Parent().SpanID() != jobSpan.SpanContext().SpanID() {
+		t.Error("get_sources should be a child of the job span")
+	}
+	if cache.Parent().SpanID() != script.SpanContext().SpanID() {
+		t.Error("restore_cache should be nested in step_script")
+	}
+	if !cache.StartTime().Equal(time.Unix(1003, 0)) || !cache.EndTime().Equal(time.Unix(1004, 0)) {
+		t.Errorf("unexpected restore_cache timing: %v - %v", cache.StartTime(), cache.EndTime())
+	}
+}
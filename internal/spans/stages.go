@@ -0,0 +1,127 @@
+package spans
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/gitlab"
+	otelutil "gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/otel"
+	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/pkg/semconv"
+)
+
+// statusSeverity ranks job statuses so the worst one decides the stage status
+var statusSeverity = map[string]int{
+	"failed":   4,
+	"canceled": 3,
+	"running":  2,
+	"success":  1,
+}
+
+// stageGroup holds the jobs and bridges of one pipeline stage
+type stageGroup struct {
+	name     string
+	start    *time.Time
+	end      *time.Time
+	status   string
+	jobCount int
+	jobs     []*gitlab.JobData
+	bridges  []*gitlab.BridgeData
+}
+
+func (s *stageGroup) add(status string, startedAt, finishedAt *time.Time) {
+	s.jobCount++
+	if s.status == "" || statusSeverity[status] > statusSeverity[s.status] {
+		s.status = status
+	}
+	s.extend(startedAt, finishedAt)
+}
+
+// extend widens the stage to cover a run
+func (s *stageGroup) extend(startedAt, finishedAt *time.Time) {
+	if startedAt == nil || finishedAt == nil {
+		return
+	}
+	if s.start == nil || startedAt.Before(*s.start) {
+		s.start = startedAt
+	}
+	if s.end == nil || finishedAt.After(*s.end) {
+		s.end = finishedAt
+	}
+}
+
+// groupByStage groups jobs and bridges by stage, ordered by stage start time.
+// Stages where nothing ran are ordered last.
+func groupByStage(jobs []*gitlab.JobData, bridges []*gitlab.BridgeData) []*stageGroup {
+	var stages []*stageGroup
+	byName := map[string]*stageGroup{}
+	stageFor := func(name string) *stageGroup {
+		if stage, ok := byName[name]; ok {
+			return stage
+		}
+		stage := &stageGroup{name: name}
+		byName[name] = stage
+		stages = append(stages, stage)
+		return stage
+	}
+
+	for _, job := range jobs {
+		stage := stageFor(job.Stage)
+		stage.jobs = append(stage.jobs, job)
+		// Only the last attempt of a job counts towards the stage status,
+		// so a flaky job that passed on retry leaves the stage green
+		switch {
+		case job.Retried:
+			stage.extend(job.StartedAt, job.FinishedAt)
+		case job.Status == "failed" && job.AllowFailure:
+			// GitLab shows the stage as passed with warnings
+			stage.add("success", job.StartedAt, job.FinishedAt)
+		default:
+			stage.add(job.Status, job.StartedAt, job.FinishedAt)
+		}
+	}
+	for _, bridge := range bridges {
+		stage := stageFor(bridge.Stage)
+		stage.bridges = append(stage.bridges, bridge)
+		stage.add(bridge.Status, bridge.StartedAt, bridge.FinishedAt)
+	}
+
+	sort.SliceStable(stages, func(i, j int) bool {
+		if stages[i].start == nil || stages[j].start == nil {
+			return stages[j].start == nil && stages[i].start != nil
+		}
+		return stages[i].start.Before(*stages[j].start)
+	})
+	return stages
+}
+
+// createStageSpan starts a span covering the first job start to the last job
+// finish of the stage. It returns a nil span when no job of the stage ran.
+func (e *Exporter) createStageSpan(ctx context.Context, pipeline *gitlab.PipelineData, stage *stageGroup) (context.Context, trace.Span) {
+	if stage.start == nil || stage.end == nil {
+		return ctx, nil
+	}
+
+	spanName := fmt.Sprintf("Stage: %s", stage.name)
+	ctx, stageSpan := e.startSpan(ctx, otelutil.StageIDKey(pipeline.ProjectID, pipeline.ID, stage.name), spanName,
+		trace.WithTimestamp(*stage.start),
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(
+			attribute.String("cicd.pipeline.stage.name", stage.name),
+			attribute.String("cicd.pipeline.stage.status", stage.status),
+			attribute.Int("cicd.pipeline.stage.job.count", stage.jobCount),
+		),
+	)
+	fmt.Printf("  Stage: %s (%s)\n", stage.name, stage.status)
+
+	return ctx, stageSpan
+}
+
+func (e *Exporter) endStageSpan(stageSpan trace.Span, stage *stageGroup) {
+	status := semconv.StatusOf(stage.status, "stage")
+	stageSpan.SetStatus(status.Code, status.Description)
+	stageSpan.End(trace.WithTimestamp(*stage.end))
+}
@@ -0,0 +1,100 @@
+package spans
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/config"
+	gitlabpkg "gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/gitlab"
+)
+
+func newStageJob(id int, stage, status string, started, finished time.Time) *gitlabpkg.JobData {
+	return &gitlabpkg.JobData{
+		Job: &gitlab.Job{
+			ID:         id,
+			Name:       stage,
+			Stage:      stage,
+			Status:     status,
+			StartedAt:  &started,
+			FinishedAt: &finished,
+		},
+		Raw: map[string]interface{}{},
+	}
+}
+
+func TestGroupByStage(t *testing.T) {
+	base := time.Now()
+	jobs := []*gitlabpkg.JobData{
+		newStageJob(3, "test", "failed", base.Add(3*time.Minute), base.Add(5*time.Minute)),
+		newStageJob(2, "test", "success", base.Add(2*time.Minute), base.Add(4*time.Minute)),
+		newStageJob(1, "build", "success", base, base.Add(time.Minute)),
+	}
+
+	stages := groupByStage(jobs, nil)
+	if len(stages) != 2 {
+		t.Fatalf("expected 2 stages, got %d", len(stages))
+	}
+	if stages[0].name != "build" || stages[1].name != "test" {
+		t.Errorf("stages should be ordered by start time, got %s, %s", stages[0].name, stages[1].name)
+	}
+
+	test := stages[1]
+	if !test.start.Equal(base.Add(2*time.Minute)) || !test.end.Equal(base.Add(5*time.Minute)) {
+		t.Errorf("test stage should span first start to last finish, got %v - %v", test.start, test.end)
+	}
+	if test.status != "failed" {
+		t.Errorf("test stage status should be the worst job status, got %s", test.status)
+	}
+	if test.jobCount != 2 {
+		t.Errorf("test stage should have 2 jobs, got %d", test.jobCount)
+	}
+}
+
+func TestExportPipelineChildrenWithStageSpans(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSyncer(exporter),
+	)
+	otel.SetTracerProvider(tp)
+	defer func() { _ = tp.Shutdown(context.Background()) }()
+
+	spanExporter := &Exporter{
+		config: &config.Config{StageSpans: true},
+		tracer: otel.Tracer("test"),
+	}
+
+	base := time.Now()
+	pipeline := &gitlabpkg.PipelineData{Pipeline: &gitlab.Pipeline{ID: 10, ProjectID: 1}}
+	jobs := []*gitlabpkg.JobData{
+		newStageJob(1, "build", "success", base, base.Add(time.Minute)),
+		newStageJob(2, "test", "failed", base.Add(time.Minute), base.Add(2*time.Minute)),
+	}
+	spanExporter.exportPipelineChildren(context.Background(), pipeline, jobs, nil, 0)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 4 {
+		t.Fatalf("expected 2 stage and 2 job spans, got %d", len(spans))
+	}
+
+	byName := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range spans.Snapshots() {
+		byName[span.Name()] = span
+	}
+	stage := byName["Stage: test"]
+	job := byName["Stage: test - job_id: 2"]
+	if stage == nil || job == nil {
+		t.Fatalf("missing stage or job span: %v", byName)
+	}
+	if job.Parent().SpanID() != stage.SpanContext().SpanID() {
+		t.Error("job span should be a child of its stage span")
+	}
+	if stage.Status().Code != codes.Error {
+		t.Errorf("stage with failed job should have error status, got %v", stage.Status().Code)
+	}
+}
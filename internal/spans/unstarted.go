@@ -0,0 +1,140 @@
+package spans
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/gitlab"
+	otelutil "gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/otel"
+	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/pkg/semconv"
+)
+
+// unstartedReasons explains, by job status, why a job never started
+var unstartedReasons = map[string]string{
+	"manual":               "waiting for manual action",
+	"skipped":              "skipped",
+	"canceled":             "canceled before start",
+	"canceling":            "canceled before start",
+	"created":              "never started",
+	"scheduled":            "waiting for delayed start",
+	"waiting_for_resource": "waiting for resource group",
+	"pending":              "waiting for runner",
+	"preparing":            "waiting for runner",
+}
+
+// unstartedReason explains, from its status, why a job or bridge never
+// started
+func unstartedReason(status string) string {
+	if reason, ok := unstartedReasons[status]; ok {
+		return reason
+	}
+	return status
+}
+
+// createUnstartedJobSpan records a job that never started as a zero-length
+// span at its creation time
+func (e *Exporter) createUnstartedJobSpan(ctx context.Context, job *gitlab.JobData) {
+	at := firstTime(job.CreatedAt, job.FinishedAt)
+	if at == nil {
+		return
+	}
+
+	status := semconv.JobStatus(job)
+	attrs := semconv.JobAttributes(job)
+	attrs = append(attrs, status.Attributes("cicd.pipeline.task.run.result")...)
+	attrs = append(attrs, attribute.String("cicd.pipeline.task.run.unstarted_reason", unstartedReason(job.Status)))
+
+	spanName := fmt.Sprintf("Stage: %s - job_id: %d", job.Name, job.ID)
+	_, jobSpan := e.startSpan(ctx, otelutil.JobIDKey(job.ID), spanName,
+		trace.WithTimestamp(*at),
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(attrs...),
+	)
+	fmt.Printf("  Job: %s (%s, not started)\n", job.Name, job.Status)
+	if e.jobSpans != nil {
+		e.jobSpans[job.ID] = jobSpan.SpanContext()
+	}
+
+	jobSpan.SetStatus(status.Code, status.Description)
+	jobSpan.End(trace.WithTimestamp(*at))
+}
+
+// createUnstartedBridgeSpan records a bridge that never started, such as a
+// manual trigger nobody played, as a zero-length span at its creation time
+func (e *Exporter) createUnstartedBridgeSpan(ctx context.Context, bridge *gitlab.BridgeData) {
+	at := firstTime(bridge.CreatedAt, bridge.FinishedAt)
+	if at == nil {
+		return
+	}
+
+	status := semconv.BridgeStatus(bridge)
+	attrs := semconv.BridgeAttributes(bridge)
+	attrs = append(attrs, status.Attributes("cicd.pipeline.task.run.result")...)
+	attrs = append(attrs, attribute.String("cicd.pipeline.task.run.unstarted_reason", unstartedReason(bridge.Status)))
+
+	spanName := fmt.Sprintf("Trigger: %s - bridge_id: %d", bridge.Name, bridge.ID)
+	_, bridgeSpan := e.startSpan(ctx, otelutil.JobIDKey(bridge.ID), spanName,
+		trace.WithTimestamp(*at),
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(attrs...),
+	)
+	fmt.Printf("  Bridge: %s (%s, not started)\n", bridge.Name, bridge.Status)
+
+	bridgeSpan.SetStatus(status.Code, status.Description)
+	bridgeSpan.End(trace.WithTimestamp(*at))
+}
+
+// addUnstartedJobEvents records the jobs and bridges of a pipeline that never
+// started as events on its span, at their creation time
+func (e *Exporter) addUnstartedJobEvents(pipelineSpan trace.Span, pipeline *gitlab.PipelineData, jobs []*gitlab.JobData, bridges []*gitlab.BridgeData) {
+	for _, job := range jobs {
+		if job.StartedAt != nil {
+			continue
+		}
+		addUnstartedEvent(pipelineSpan, semconv.JobStatus(job), firstTime(job.CreatedAt, pipeline.CreatedAt),
+			attribute.String("cicd.pipeline.task.name", job.Name),
+			attribute.String("cicd.pipeline.task.run.id", strconv.Itoa(job.ID)),
+			attribute.String("cicd.pipeline.task.run.status", job.Status),
+			attribute.String("cicd.pipeline.task.run.unstarted_reason", unstartedReason(job.Status)),
+			attribute.String("cicd.pipeline.task.type", "build"),
+			attribute.String("stage", job.Stage),
+		)
+	}
+	for _, bridge := range bridges {
+		if bridge.StartedAt != nil {
+			continue
+		}
+		addUnstartedEvent(pipelineSpan, semconv.BridgeStatus(bridge), firstTime(bridge.CreatedAt, pipeline.CreatedAt),
+			attribute.String("cicd.pipeline.task.name", bridge.Name),
+			attribute.String("cicd.pipeline.task.run.id", strconv.Itoa(bridge.ID)),
+			attribute.String("cicd.pipeline.task.run.status", bridge.Status),
+			attribute.String("cicd.pipeline.task.run.unstarted_reason", unstartedReason(bridge.Status)),
+			attribute.String("cicd.pipeline.task.type", "trigger"),
+			attribute.String("stage", bridge.Stage),
+		)
+	}
+}
+
+// addUnstartedEvent adds a "job not started" event with the given attributes
+// and the run's result, at the given time if known
+func addUnstartedEvent(pipelineSpan trace.Span, status semconv.RunStatus, at *time.Time, attrs ...attribute.KeyValue) {
+	attrs = append(attrs, status.Attributes("cicd.pipeline.task.run.result")...)
+	opts := []trace.EventOption{trace.WithAttributes(attrs...)}
+	if at != nil {
+		opts = append(opts, trace.WithTimestamp(*at))
+	}
+	pipelineSpan.AddEvent("job not started", opts...)
+}
+
+func firstTime(times ...*time.Time) *time.Time {
+	for _, t := range times {
+		if t != nil {
+			return t
+		}
+	}
+	return nil
+}
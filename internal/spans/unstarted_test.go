@@ -0,0 +1,131 @@
+package spans
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/config"
+	gitlabpkg "gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/gitlab"
+)
+
+// exportUnstartedJobs exports a pipeline span with one finished job, a
+// manual job nobody played, a skipped job and a manual trigger nobody played
+// under the given policy
+func exportUnstartedJobs(t *testing.T, policy string) map[string]sdktrace.ReadOnlySpan {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSyncer(exporter),
+	)
+	otel.SetTracerProvider(tp)
+	defer func() { _ = tp.Shutdown(context.Background()) }()
+
+	spanExporter := &Exporter{
+		config:   &config.Config{UnstartedJobs: policy},
+		tracer:   otel.Tracer("test"),
+		jobSpans: map[int]trace.SpanContext{},
+	}
+
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	created := base.Add(time.Minute)
+	pipeline := &gitlabpkg.PipelineData{Pipeline: &gitlab.Pipeline{ID: 10, ProjectID: 1, CreatedAt: &base}}
+	jobs := []*gitlabpkg.JobData{
+		newStageJob(1, "build", "success", base, base.Add(time.Minute)),
+		{Job: &gitlab.Job{ID: 2, Name: "release", Stage: "deploy", Status: "manual", CreatedAt: &created}},
+		{Job: &gitlab.Job{ID: 3, Name: "cleanup", Stage: "deploy", Status: "skipped", CreatedAt: &created}},
+	}
+
+	bridges := []*gitlabpkg.BridgeData{
+		{Bridge: &gitlab.Bridge{ID: 4, Name: "approve", Stage: "deploy", Status: "manual", CreatedAt: &created}},
+	}
+
+	ctx, pipelineSpan := otel.Tracer("test").Start(context.Background(), "pipeline")
+	spanExporter.exportPipelineChildren(ctx, pipeline, jobs, bridges, 0)
+	pipelineSpan.End()
+
+	byName := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range exporter.GetSpans().Snapshots() {
+		byName[span.Name()] = span
+	}
+	return byName
+}
+
+func attributeOf(attrs []attribute.KeyValue, key string) string {
+	for _, attr := range attrs {
+		if string(attr.Key) == key {
+			return attr.Value.Emit()
+		}
+	}
+	return ""
+}
+
+func TestUnstartedJobsDropped(t *testing.T) {
+	spans := exportUnstartedJobs(t, "drop")
+	if len(spans) != 2 {
+		t.Errorf("expected only the pipeline and build spans, got %d spans", len(spans))
+	}
+	if events := spans["pipeline"].Events(); len(events) != 0 {
+		t.Errorf("expected no events, got %d", len(events))
+	}
+}
+
+func TestUnstartedJobsAsSpans(t *testing.T) {
+	spans := exportUnstartedJobs(t, "span")
+
+	manual := spans["Stage: release - job_id: 2"]
+	if manual == nil {
+		t.Fatalf("missing span of the manual job, got %d spans", len(spans))
+	}
+	if !manual.StartTime().Equal(manual.EndTime()) {
+		t.Errorf("expected a zero-length span, got %s", manual.EndTime().Sub(manual.StartTime()))
+	}
+	if got := attributeOf(manual.Attributes(), "cicd.pipeline.task.run.unstarted_reason"); got != "waiting for manual action" {
+		t.Errorf("unexpected reason %q", got)
+	}
+	if got := attributeOf(manual.Attributes(), "cicd.pipeline.task.run.result"); got != "skip" {
+		t.Errorf("expected result skip, got %q", got)
+	}
+	if spans["Stage: cleanup - job_id: 3"] == nil {
+		t.Error("missing span of the skipped job")
+	}
+
+	trigger := spans["Trigger: approve - bridge_id: 4"]
+	if trigger == nil {
+		t.Fatal("missing span of the manual trigger")
+	}
+	if !trigger.StartTime().Equal(trigger.EndTime()) {
+		t.Errorf("expected a zero-length bridge span, got %s", trigger.EndTime().Sub(trigger.StartTime()))
+	}
+	if got := attributeOf(trigger.Attributes(), "cicd.pipeline.task.run.unstarted_reason"); got != "waiting for manual action" {
+		t.Errorf("unexpected bridge reason %q", got)
+	}
+	if got := attributeOf(trigger.Attributes(), "cicd.pipeline.task.type"); got != "trigger" {
+		t.Errorf("expected task type trigger, got %q", got)
+	}
+}
+
+func TestUnstartedJobsAsEvents(t *testing.T) {
+	spans := exportUnstartedJobs(t, "event")
+	if len(spans) != 2 {
+		t.Errorf("expected only the pipeline and build spans, got %d spans", len(spans))
+	}
+
+	events := spans["pipeline"].Events()
+	if len(events) != 3 {
+		t.Fatalf("expected one event per unstarted job and bridge, got %d", len(events))
+	}
+	reasons := map[string]string{}
+	for _, event := range events {
+		reasons[attributeOf(event.Attributes, "cicd.pipeline.task.name")] = attributeOf(event.Attributes, "cicd.pipeline.task.run.unstarted_reason")
+	}
+	if reasons["release"] != "waiting for manual action" || reasons["cleanup"] != "skipped" || reasons["approve"] != "waiting for manual action" {
+		t.Errorf("unexpected event reasons %v", reasons)
+	}
+}
@@ -0,0 +1,195 @@
+package webhook
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/config"
+	gitlabpkg "gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/gitlab"
+)
+
+const (
+	// maxPayloadSize bounds the webhook body read into memory
+	maxPayloadSize = 10 * 1024 * 1024
+
+	// exportedRetention is how long an exported pipeline is remembered to
+	// suppress duplicate exports from repeated hooks
+	exportedRetention = 24 * time.Hour
+)
+
+// ExportFunc exports the trace of one pipeline
+type ExportFunc func(ctx context.Context, projectID string, pipelineID int) error
+
+type pipelineRef struct {
+	projectID  string
+	pipelineID int
+}
+
+// Server receives GitLab Pipeline Hook and Job Hook webhooks and exports the
+// trace of a pipeline once it reaches a terminal state. Exports run on a
+// pool of workers so that GitLab gets its response right away.
+type Server struct {
+	secret  string
+	workers int
+	source  gitlabpkg.PipelineSource
+	export  ExportFunc
+
+	queue chan pipelineRef
+	wg    sync.WaitGroup
+
+	mu       sync.Mutex
+	exported map[string]time.Time
+}
+
+// NewServer creates a webhook server
+func NewServer(cfg *config.Config, source gitlabpkg.PipelineSource, export ExportFunc) *Server {
+	workers := cfg.WebhookWorkers
+	if workers < 1 {
+		workers = 1
+	}
+
+	return &Server{
+		secret:   cfg.WebhookSecret,
+		workers:  workers,
+		source:   source,
+		export:   export,
+		queue:    make(chan pipelineRef, 100*workers),
+		exported: make(map[string]time.Time),
+	}
+}
+
+// Start starts the export workers
+func (s *Server) Start(ctx context.Context) {
+	for i := 0; i < s.workers; i++ {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			for ref := range s.queue {
+				s.process(ctx, ref)
+			}
+		}()
+	}
+}
+
+// Stop stops accepting exports and waits for queued exports to finish.
+// The HTTP server must be shut down before calling Stop.
+func (s *Server) Stop() {
+	close(s.queue)
+	s.wg.Wait()
+}
+
+// ServeHTTP handles one webhook delivery
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Gitlab-Token")), []byte(s.secret)) != 1 {
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+		return
+	}
+
+	payload, err := io.ReadAll(io.LimitReader(r.Body, maxPayloadSize))
+	if err != nil {
+		http.Error(w, "failed to read payload", http.StatusBadRequest)
+		return
+	}
+
+	eventType := gitlab.HookEventType(r)
+	if eventType != gitlab.EventTypePipeline && eventType != gitlab.EventTypeJob {
+		// Acknowledge other events so GitLab does not disable the hook
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	event, err := gitlab.ParseWebhook(eventType, payload)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid payload: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	var ref pipelineRef
+	var status string
+	switch event := event.(type) {
+	case *gitlab.PipelineEvent:
+		ref = pipelineRef{strconv.Itoa(event.Project.ID), event.ObjectAttributes.ID}
+		status = event.ObjectAttributes.Status
+	case *gitlab.JobEvent:
+		ref = pipelineRef{strconv.Itoa(event.ProjectID), event.PipelineID}
+		status = event.BuildStatus
+	}
+
+	if !gitlabpkg.IsTerminalStatus(status) || ref.pipelineID == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	select {
+	case s.queue <- ref:
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		log.Printf("export queue full, dropping pipeline %d of project %s", ref.pipelineID, ref.projectID)
+		http.Error(w, "export queue full", http.StatusServiceUnavailable)
+	}
+}
+
+// process exports a pipeline if it is finished and was not exported yet.
+// The pipeline is fetched first because a finished job does not mean the
+// pipeline is finished.
+func (s *Server) process(ctx context.Context, ref pipelineRef) {
+	pipeline, err := s.source.FetchPipelineByID(ctx, ref.projectID, ref.pipelineID)
+	if err != nil {
+		log.Printf("failed to fetch pipeline %d of project %s: %v", ref.pipelineID, ref.projectID, err)
+		return
+	}
+	if !gitlabpkg.IsTerminalStatus(pipeline.Status) {
+		return
+	}
+
+	// A retried job makes a finished pipeline run again, so the key includes
+	// the finish time to export each completion once
+	key := fmt.Sprintf("%s/%d/%v", ref.projectID, ref.pipelineID, pipeline.FinishedAt)
+	if !s.markExported(key) {
+		return
+	}
+
+	if err := s.export(ctx, ref.projectID, ref.pipelineID); err != nil {
+		log.Printf("failed to export pipeline %d of project %s: %v", ref.pipelineID, ref.projectID, err)
+		s.unmarkExported(key)
+		return
+	}
+	fmt.Printf("Exported pipeline %d of project %s\n", ref.pipelineID, ref.projectID)
+}
+
+// markExported records key as exported, returning false if it already was
+func (s *Server) markExported(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for k, at := range s.exported {
+		if now.Sub(at) > exportedRetention {
+			delete(s.exported, k)
+		}
+	}
+
+	if _, ok := s.exported[key]; ok {
+		return false
+	}
+	s.exported[key] = now
+	return true
+}
+
+func (s *Server) unmarkExported(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.exported, key)
+}
@@ -0,0 +1,108 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/config"
+	gitlabpkg "gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/gitlab"
+)
+
+func newTestServer(t *testing.T, pipelineStatus map[string]string) (*Server, *[]int, *sync.Mutex) {
+	t.Helper()
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status, ok := pipelineStatus[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"id": 20, "status": status, "finished_at": "2026-01-01T00:00:00Z",
+		})
+	}))
+	t.Cleanup(api.Close)
+
+	cfg := &config.Config{ServerURL: api.URL, WebhookSecret: "s3cret", WebhookWorkers: 1}
+	gitClient, err := gitlabpkg.NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	var mu sync.Mutex
+	var exported []int
+	server := NewServer(cfg, gitClient, func(_ context.Context, _ string, pipelineID int) error {
+		mu.Lock()
+		defer mu.Unlock()
+		exported = append(exported, pipelineID)
+		return nil
+	})
+	return server, &exported, &mu
+}
+
+func deliver(server *Server, token, event, body string) int {
+	req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+	req.Header.Set("X-Gitlab-Token", token)
+	req.Header.Set("X-Gitlab-Event", event)
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestServerRejectsInvalidToken(t *testing.T) {
+	server, _, _ := newTestServer(t, nil)
+
+	if code := deliver(server, "wrong", "Pipeline Hook", `{}`); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for invalid token, got %d", code)
+	}
+}
+
+func TestServerExportsFinishedPipelineOnce(t *testing.T) {
+	server, exported, mu := newTestServer(t, map[string]string{
+		"/api/v4/projects/10/pipelines/20": "success",
+	})
+	server.Start(context.Background())
+
+	running := `{"object_kind":"pipeline","object_attributes":{"id":20,"status":"running"},"project":{"id":10}}`
+	if code := deliver(server, "s3cret", "Pipeline Hook", running); code != http.StatusNoContent {
+		t.Errorf("expected 204 for running pipeline, got %d", code)
+	}
+
+	finished := `{"object_kind":"pipeline","object_attributes":{"id":20,"status":"success"},"project":{"id":10}}`
+	for i := 0; i < 2; i++ {
+		if code := deliver(server, "s3cret", "Pipeline Hook", finished); code != http.StatusAccepted {
+			t.Errorf("expected 202 for finished pipeline, got %d", code)
+		}
+	}
+	server.Stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(*exported) != 1 || (*exported)[0] != 20 {
+		t.Errorf("expected pipeline 20 to be exported once, got %v", *exported)
+	}
+}
+
+func TestServerJobHookWaitsForPipeline(t *testing.T) {
+	server, exported, mu := newTestServer(t, map[string]string{
+		"/api/v4/projects/10/pipelines/20": "running",
+	})
+	server.Start(context.Background())
+
+	job := `{"object_kind":"build","build_status":"success","pipeline_id":20,"project_id":10}`
+	if code := deliver(server, "s3cret", "Job Hook", job); code != http.StatusAccepted {
+		t.Errorf("expected 202 for finished job, got %d", code)
+	}
+	server.Stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(*exported) != 0 {
+		t.Errorf("pipeline still running should not be exported, got %v", *exported)
+	}
+}
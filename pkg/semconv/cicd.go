@@ -2,23 +2,43 @@ package semconv
 
 import (
 	"fmt"
-	"os"
 
 	"go.opentelemetry.io/otel/attribute"
+	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/config"
 	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/gitlab"
 	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/utils"
 )
 
 // PipelineAttributes returns CI/CD semantic convention attributes for pipeline
-func PipelineAttributes() []attribute.KeyValue {
+func PipelineAttributes(cfg *config.Config) []attribute.KeyValue {
 	return []attribute.KeyValue{
-		attribute.String("cicd.pipeline.name", os.Getenv("CI_PIPELINE_NAME")),
-		attribute.String("cicd.pipeline.run.id", os.Getenv("CI_PIPELINE_ID")),
-		attribute.String("vcs.repository.url.full", os.Getenv("CI_PROJECT_URL")),
-		attribute.String("vcs.repository.ref.name", os.Getenv("CI_COMMIT_REF_NAME")),
-		attribute.String("vcs.repository.ref.revision", os.Getenv("CI_COMMIT_SHA")),
-		attribute.String("vcs.repository.ref.type", RefType()),
-		attribute.String("cicd.pipeline.trigger.type", TriggerType()),
+		attribute.String("cicd.pipeline.name", cfg.PipelineName),
+		attribute.String("cicd.pipeline.run.id", cfg.PipelineID),
+		attribute.String("vcs.repository.url.full", cfg.ProjectURL),
+		attribute.String("vcs.repository.ref.name", cfg.CommitRefName),
+		attribute.String("vcs.repository.ref.revision", cfg.CommitSHA),
+		attribute.String("vcs.repository.ref.type", RefType(cfg)),
+		attribute.String("cicd.pipeline.trigger.type", TriggerType(cfg)),
+	}
+}
+
+// PipelineDataAttributes returns CI/CD semantic convention attributes derived
+// from pipeline API data rather than the CI environment, for pipelines other
+// than the one the exporter runs in
+func PipelineDataAttributes(pipeline *gitlab.PipelineData) []attribute.KeyValue {
+	refType := "branch"
+	if pipeline.Tag {
+		refType = "tag"
+	}
+
+	return []attribute.KeyValue{
+		attribute.String("cicd.pipeline.name", pipeline.Name),
+		attribute.String("cicd.pipeline.run.id", fmt.Sprintf("%d", pipeline.ID)),
+		attribute.String("vcs.repository.url.full", pipeline.ProjectURL()),
+		attribute.String("vcs.repository.ref.name", pipeline.Ref),
+		attribute.String("vcs.repository.ref.revision", pipeline.SHA),
+		attribute.String("vcs.repository.ref.type", refType),
+		attribute.String("cicd.pipeline.trigger.type", triggerType(pipeline.Source)),
 	}
 }
 
@@ -31,22 +51,87 @@ func JobAttributes(job *gitlab.JobData) []attribute.KeyValue {
 		attribute.String("cicd.pipeline.task.type", "build"),
 		attribute.String("stage", job.Stage),
 	}
+	if job.Attempt > 0 {
+		attrs = append(attrs,
+			attribute.Int("cicd.pipeline.task.run.attempt", job.Attempt),
+			attribute.Bool("cicd.pipeline.task.run.retried", job.Retried),
+		)
+	}
+	if len(job.Needs) > 0 {
+		needs := make([]string, 0, len(job.Needs))
+		for _, need := range job.Needs {
+			needs = append(needs, need.Name)
+		}
+		attrs = append(attrs, attribute.StringSlice("cicd.pipeline.task.needs", needs))
+	}
 
 	attrs = append(attrs, utils.FlattenMap("", job.Raw)...)
 	return attrs
 }
 
+// BridgeAttributes returns CI/CD semantic convention attributes for a bridge
+// (trigger job), including the downstream pipeline it started
+func BridgeAttributes(bridge *gitlab.BridgeData) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		attribute.String("cicd.pipeline.task.name", bridge.Name),
+		attribute.String("cicd.pipeline.task.run.id", fmt.Sprintf("%d", bridge.ID)),
+		attribute.String("cicd.pipeline.task.run.url.full", bridge.WebURL),
+		attribute.String("cicd.pipeline.task.type", "trigger"),
+		attribute.String("stage", bridge.Stage),
+	}
+
+	if downstream := bridge.DownstreamPipeline; downstream != nil {
+		attrs = append(attrs,
+			attribute.String("cicd.pipeline.downstream.project.id", fmt.Sprintf("%d", downstream.ProjectID)),
+			attribute.String("cicd.pipeline.downstream.run.id", fmt.Sprintf("%d", downstream.ID)),
+			attribute.String("cicd.pipeline.downstream.status", downstream.Status),
+			attribute.String("cicd.pipeline.downstream.url.full", downstream.WebURL),
+		)
+	}
+
+	attrs = append(attrs, utils.FlattenMap("", bridge.Raw)...)
+	return attrs
+}
+
+// PipelineMetricAttributes returns the low-cardinality CI/CD semantic
+// convention attributes used on pipeline metrics
+func PipelineMetricAttributes(pipeline *gitlab.PipelineData) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("cicd.pipeline.name", pipeline.Name),
+		attribute.String("vcs.repository.url.full", pipeline.ProjectURL()),
+		attribute.String("cicd.pipeline.trigger.type", triggerType(pipeline.Source)),
+		attribute.String("cicd.pipeline.run.status", pipeline.Status),
+	}
+}
+
+// JobMetricAttributes returns the low-cardinality CI/CD semantic convention
+// attributes used on job metrics
+func JobMetricAttributes(job *gitlab.JobData) []attribute.KeyValue {
+	worker := job.Runner.Name
+	if worker == "" {
+		worker = job.Runner.Description
+	}
+
+	return []attribute.KeyValue{
+		attribute.String("cicd.pipeline.task.name", job.Name),
+		attribute.String("cicd.pipeline.task.run.status", job.Status),
+		attribute.Bool("cicd.pipeline.task.run.retried", job.Retried),
+		attribute.String("cicd.worker.name", worker),
+		attribute.String("stage", job.Stage),
+	}
+}
+
 // ParentPipelineAttributes returns attributes for parent pipeline correlation
-func ParentPipelineAttributes(gitClient *gitlab.Client, pipeline *gitlab.PipelineData) []attribute.KeyValue {
+func ParentPipelineAttributes(cfg *config.Config, pipeline *gitlab.PipelineData) []attribute.KeyValue {
 	var attrs []attribute.KeyValue
 
 	// Add parent pipeline info for downstream pipelines
-	if os.Getenv("CI_PIPELINE_SOURCE") == "pipeline" || os.Getenv("CI_PIPELINE_SOURCE") == "trigger" {
-		if parentPipelineID := os.Getenv("CI_PARENT_PIPELINE_ID"); parentPipelineID != "" {
-			attrs = append(attrs, attribute.String("cicd.pipeline.parent.id", parentPipelineID))
+	if cfg.PipelineSource == "pipeline" || cfg.PipelineSource == "trigger" {
+		if cfg.ParentPipelineID != "" {
+			attrs = append(attrs, attribute.String("cicd.pipeline.parent.id", cfg.ParentPipelineID))
 		}
-		if parentProjectID := os.Getenv("CI_PARENT_PROJECT_ID"); parentProjectID != "" {
-			attrs = append(attrs, attribute.String("cicd.pipeline.parent.project.id", parentProjectID))
+		if cfg.ParentProjectID != "" {
+			attrs = append(attrs, attribute.String("cicd.pipeline.parent.project.id", cfg.ParentProjectID))
 		}
 
 		// Try to get more parent info from API if available
@@ -60,23 +145,27 @@ func ParentPipelineAttributes(gitClient *gitlab.Client, pipeline *gitlab.Pipelin
 }
 
 // RefType determines if the reference is a branch or tag
-func RefType() string {
-	if os.Getenv("CI_COMMIT_TAG") != "" {
+func RefType(cfg *config.Config) string {
+	if cfg.CommitTag != "" {
 		return "tag"
 	}
 	return "branch"
 }
 
 // TriggerType determines the pipeline trigger type
-func TriggerType() string {
-	switch os.Getenv("CI_PIPELINE_SOURCE") {
+func TriggerType(cfg *config.Config) string {
+	return triggerType(cfg.PipelineSource)
+}
+
+func triggerType(source string) string {
+	switch source {
 	case "push":
 		return "scm.push"
 	case "merge_request_event":
 		return "scm.pull_request"
 	case "schedule":
 		return "schedule"
-	case "trigger", "pipeline":
+	case "trigger", "pipeline", "parent_pipeline":
 		return "other_pipeline"
 	default:
 		return "manual"
@@ -1,10 +1,10 @@
 package semconv
 
 import (
-	"os"
 	"testing"
 
 	gitlab "gitlab.com/gitlab-org/api/client-go"
+	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/config"
 	gitlabpkg "gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/gitlab"
 )
 
@@ -18,9 +18,7 @@ func TestRefType(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		_ = os.Setenv("CI_COMMIT_TAG", tt.tag)
-		defer func() { _ = os.Unsetenv("CI_COMMIT_TAG") }()
-		if got := RefType(); got != tt.want {
+		if got := RefType(&config.Config{CommitTag: tt.tag}); got != tt.want {
 			t.Errorf("RefType() = %q, want %q", got, tt.want)
 		}
 	}
@@ -40,21 +38,19 @@ func TestTriggerType(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		_ = os.Setenv("CI_PIPELINE_SOURCE", tt.source)
-		defer func() { _ = os.Unsetenv("CI_PIPELINE_SOURCE") }()
-		if got := TriggerType(); got != tt.want {
+		if got := TriggerType(&config.Config{PipelineSource: tt.source}); got != tt.want {
 			t.Errorf("TriggerType() with source %q = %q, want %q", tt.source, got, tt.want)
 		}
 	}
 }
 
 func TestPipelineAttributes(t *testing.T) {
-	_ = os.Setenv("CI_PIPELINE_NAME", "test-pipeline")
-	_ = os.Setenv("CI_PIPELINE_ID", "123")
-	defer func() { _ = os.Unsetenv("CI_PIPELINE_NAME") }()
-	defer func() { _ = os.Unsetenv("CI_PIPELINE_ID") }()
+	cfg := &config.Config{
+		PipelineName: "test-pipeline",
+		PipelineID:   "123",
+	}
 
-	attrs := PipelineAttributes()
+	attrs := PipelineAttributes(cfg)
 	if len(attrs) != 7 {
 		t.Errorf("PipelineAttributes() returned %d attributes, want 7", len(attrs))
 	}
@@ -85,22 +81,17 @@ func TestJobAttributes(t *testing.T) {
 
 func TestParentPipelineAttributes(t *testing.T) {
 	// Test non-triggered pipeline
-	_ = os.Setenv("CI_PIPELINE_SOURCE", "push")
-	defer func() { _ = os.Unsetenv("CI_PIPELINE_SOURCE") }()
-
-	attrs := ParentPipelineAttributes(nil, nil)
+	attrs := ParentPipelineAttributes(&config.Config{PipelineSource: "push"}, nil)
 	if len(attrs) != 0 {
 		t.Errorf("non-triggered pipeline should have no parent attributes, got %d", len(attrs))
 	}
 
 	// Test triggered pipeline with parent info
-	_ = os.Setenv("CI_PIPELINE_SOURCE", "trigger")
-	_ = os.Setenv("CI_PARENT_PIPELINE_ID", "123")
-	_ = os.Setenv("CI_PARENT_PROJECT_ID", "456")
-	defer func() {
-		_ = os.Unsetenv("CI_PARENT_PIPELINE_ID")
-		_ = os.Unsetenv("CI_PARENT_PROJECT_ID")
-	}()
+	cfg := &config.Config{
+		PipelineSource:   "trigger",
+		ParentPipelineID: "123",
+		ParentProjectID:  "456",
+	}
 
 	pipeline := &gitlabpkg.PipelineData{
 		Pipeline: &gitlab.Pipeline{
@@ -108,7 +99,7 @@ func TestParentPipelineAttributes(t *testing.T) {
 		},
 	}
 
-	attrs = ParentPipelineAttributes(nil, pipeline)
+	attrs = ParentPipelineAttributes(cfg, pipeline)
 	if len(attrs) != 3 {
 		t.Errorf("triggered pipeline should have 3 parent attributes, got %d", len(attrs))
 	}
@@ -139,3 +130,94 @@ func TestParentPipelineAttributes(t *testing.T) {
 		t.Errorf("not all expected attributes found: %v", found)
 	}
 }
+
+func TestBridgeAttributes(t *testing.T) {
+	bridge := &gitlabpkg.BridgeData{
+		Bridge: &gitlab.Bridge{
+			ID:     789,
+			Name:   "trigger-child",
+			Stage:  "deploy",
+			WebURL: "https://gitlab.com/test/job/789",
+			DownstreamPipeline: &gitlab.PipelineInfo{
+				ID:        1000,
+				ProjectID: 42,
+				Status:    "success",
+			},
+		},
+		Raw: map[string]interface{}{},
+	}
+
+	attrs := BridgeAttributes(bridge)
+	found := map[string]string{}
+	for _, attr := range attrs {
+		found[string(attr.Key)] = attr.Value.AsString()
+	}
+
+	if found["cicd.pipeline.downstream.run.id"] != "1000" {
+		t.Errorf("downstream.run.id should be 1000, got %q", found["cicd.pipeline.downstream.run.id"])
+	}
+	if found["cicd.pipeline.downstream.project.id"] != "42" {
+		t.Errorf("downstream.project.id should be 42, got %q", found["cicd.pipeline.downstream.project.id"])
+	}
+	if found["cicd.pipeline.downstream.status"] != "success" {
+		t.Errorf("downstream.status should be success, got %q", found["cicd.pipeline.downstream.status"])
+	}
+}
+
+func TestPipelineDataAttributes(t *testing.T) {
+	pipeline := &gitlabpkg.PipelineData{
+		Pipeline: &gitlab.Pipeline{
+			ID:     1000,
+			Ref:    "v1.0.0",
+			SHA:    "abc123",
+			Tag:    true,
+			Source: "parent_pipeline",
+			WebURL: "https://gitlab.com/group/child/-/pipelines/1000",
+		},
+	}
+
+	found := map[string]string{}
+	for _, attr := range PipelineDataAttributes(pipeline) {
+		found[string(attr.Key)] = attr.Value.AsString()
+	}
+
+	want := map[string]string{
+		"cicd.pipeline.run.id":        "1000",
+		"vcs.repository.url.full":     "https://gitlab.com/group/child",
+		"vcs.repository.ref.type":     "tag",
+		"vcs.repository.ref.revision": "abc123",
+		"cicd.pipeline.trigger.type":  "other_pipeline",
+	}
+	for key, value := range want {
+		if found[key] != value {
+			t.Errorf("%s = %q, want %q", key, found[key], value)
+		}
+	}
+}
+
+func TestJobMetricAttributes(t *testing.T) {
+	job := &gitlabpkg.JobData{
+		Job: &gitlab.Job{
+			ID:     456,
+			Name:   "test-job",
+			Stage:  "test",
+			Status: "failed",
+		},
+	}
+	job.Runner.Description = "shared-runner-1"
+
+	found := map[string]string{}
+	for _, attr := range JobMetricAttributes(job) {
+		found[string(attr.Key)] = attr.Value.AsString()
+	}
+
+	if found["cicd.worker.name"] != "shared-runner-1" {
+		t.Errorf("worker name should fall back to runner description, got %q", found["cicd.worker.name"])
+	}
+	if found["cicd.pipeline.task.run.status"] != "failed" {
+		t.Errorf("task run status should be failed, got %q", found["cicd.pipeline.task.run.status"])
+	}
+	if _, ok := found["cicd.pipeline.task.run.id"]; ok {
+		t.Error("job metric attributes should not include the high-cardinality run ID")
+	}
+}
@@ -0,0 +1,117 @@
+package semconv
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/gitlab"
+)
+
+// Results of pipeline and task runs, the values of cicd.pipeline.result and
+// cicd.pipeline.task.run.result
+const (
+	ResultSuccess      = "success"
+	ResultFailure      = "failure"
+	ResultError        = "error"
+	ResultTimeout      = "timeout"
+	ResultCancellation = "cancellation"
+	ResultSkip         = "skip"
+)
+
+// timeoutReasons are the failure reasons of jobs that ran out of time. Other
+// reasons than script_failure are problems of the CI system rather than of
+// the job, and map to the error result.
+var timeoutReasons = map[string]bool{
+	"job_execution_timeout":    true,
+	"stuck_or_timeout_failure": true,
+}
+
+// RunStatus is the outcome of a pipeline, stage or job run as a semconv
+// result and a span status
+type RunStatus struct {
+	// Result is empty while the run has not finished
+	Result string
+
+	// ErrorType is GitLab's failure reason of a failed run
+	ErrorType string
+
+	Code        codes.Code
+	Description string
+}
+
+// Attributes returns the result under resultKey, cicd.pipeline.result or
+// cicd.pipeline.task.run.result, and error.type for failed runs
+func (s RunStatus) Attributes(resultKey string) []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	if s.Result != "" {
+		attrs = append(attrs, attribute.String(resultKey, s.Result))
+	}
+	if s.ErrorType != "" {
+		attrs = append(attrs, attribute.String("error.type", s.ErrorType))
+	}
+	return attrs
+}
+
+// StatusOf maps a GitLab status. Only failures are span errors; canceled,
+// skipped and manual runs have a result but leave the span status unset, as
+// do unfinished runs, which have no result yet.
+func StatusOf(status, kind string) RunStatus {
+	switch status {
+	case "success":
+		return RunStatus{Result: ResultSuccess, Code: codes.Ok}
+	case "failed":
+		return RunStatus{Result: ResultFailure, Code: codes.Error, Description: kind + " failed"}
+	case "canceled", "canceling":
+		return RunStatus{Result: ResultCancellation}
+	case "skipped", "manual":
+		return RunStatus{Result: ResultSkip}
+	default:
+		return RunStatus{}
+	}
+}
+
+// PipelineStatus maps the status of a pipeline. A pipeline that failed on
+// its configuration is an error, described by the YAML errors.
+func PipelineStatus(pipeline *gitlab.PipelineData) RunStatus {
+	s := StatusOf(pipeline.Status, "pipeline")
+	if pipeline.Status == "failed" && pipeline.YamlErrors != "" {
+		s.Result = ResultError
+		s.ErrorType = "yaml_errors"
+		s.Description = pipeline.YamlErrors
+	}
+	return s
+}
+
+// JobStatus maps the status of a job
+func JobStatus(job *gitlab.JobData) RunStatus {
+	return taskStatus(job.Status, job.FailureReason, job.AllowFailure, "job")
+}
+
+// BridgeStatus maps the status of a bridge (trigger job)
+func BridgeStatus(bridge *gitlab.BridgeData) RunStatus {
+	return taskStatus(bridge.Status, bridge.FailureReason, bridge.AllowFailure, "bridge")
+}
+
+// taskStatus maps a job or bridge status, describing failures by their
+// failure reason. A failure that is allowed does not fail the pipeline, so it
+// keeps its result but is not a span error.
+func taskStatus(status, failureReason string, allowFailure bool, kind string) RunStatus {
+	s := StatusOf(status, kind)
+	if status != "failed" {
+		return s
+	}
+
+	if failureReason != "" {
+		s.ErrorType = failureReason
+		s.Description = failureReason
+		switch {
+		case timeoutReasons[failureReason]:
+			s.Result = ResultTimeout
+		case failureReason != "script_failure":
+			s.Result = ResultError
+		}
+	}
+	if allowFailure {
+		s.Code, s.Description = codes.Unset, ""
+	}
+	return s
+}
@@ -0,0 +1,58 @@
+package semconv
+
+import (
+	"testing"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+	"go.opentelemetry.io/otel/codes"
+	gitlabpkg "gitlab.internal.ericsson.com/ewikhen/gitlab-otel-exporter/internal/gitlab"
+)
+
+func TestJobStatus(t *testing.T) {
+	tests := []struct {
+		status        string
+		failureReason string
+		allowFailure  bool
+		want          RunStatus
+	}{
+		{"success", "", false, RunStatus{Result: ResultSuccess, Code: codes.Ok}},
+		{"failed", "", false, RunStatus{Result: ResultFailure, Code: codes.Error, Description: "job failed"}},
+		{"failed", "script_failure", false, RunStatus{Result: ResultFailure, ErrorType: "script_failure", Code: codes.Error, Description: "script_failure"}},
+		{"failed", "job_execution_timeout", false, RunStatus{Result: ResultTimeout, ErrorType: "job_execution_timeout", Code: codes.Error, Description: "job_execution_timeout"}},
+		{"failed", "runner_system_failure", false, RunStatus{Result: ResultError, ErrorType: "runner_system_failure", Code: codes.Error, Description: "runner_system_failure"}},
+		{"failed", "script_failure", true, RunStatus{Result: ResultFailure, ErrorType: "script_failure", Code: codes.Unset}},
+		{"canceled", "", false, RunStatus{Result: ResultCancellation}},
+		{"skipped", "", false, RunStatus{Result: ResultSkip}},
+		{"manual", "", false, RunStatus{Result: ResultSkip}},
+		{"created", "", false, RunStatus{}},
+		{"running", "", false, RunStatus{}},
+	}
+
+	for _, tt := range tests {
+		job := &gitlabpkg.JobData{Job: &gitlab.Job{Status: tt.status, FailureReason: tt.failureReason, AllowFailure: tt.allowFailure}}
+		if got := JobStatus(job); got != tt.want {
+			t.Errorf("JobStatus(%s, %q, allow_failure=%v) = %+v, want %+v", tt.status, tt.failureReason, tt.allowFailure, got, tt.want)
+		}
+	}
+}
+
+func TestPipelineStatus(t *testing.T) {
+	pipeline := &gitlabpkg.PipelineData{Pipeline: &gitlab.Pipeline{Status: "failed", YamlErrors: "jobs:test config should implement a script"}}
+	status := PipelineStatus(pipeline)
+	if status.Result != ResultError || status.Code != codes.Error || status.Description != pipeline.YamlErrors {
+		t.Errorf("expected a YAML error, got %+v", status)
+	}
+
+	attrs := status.Attributes("cicd.pipeline.result")
+	found := map[string]string{}
+	for _, attr := range attrs {
+		found[string(attr.Key)] = attr.Value.AsString()
+	}
+	if found["cicd.pipeline.result"] != ResultError || found["error.type"] != "yaml_errors" {
+		t.Errorf("unexpected attributes %v", found)
+	}
+
+	if attrs := StatusOf("running", "pipeline").Attributes("cicd.pipeline.result"); len(attrs) != 0 {
+		t.Errorf("expected no result for a running pipeline, got %v", attrs)
+	}
+}